@@ -0,0 +1,68 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/discordianfish/pager-hours/oncall"
+)
+
+const (
+	// attributeResponders credits an incident to whoever acknowledged or
+	// resolved it, in the hour they did so.
+	attributeResponders = "responders"
+	// attributeSchedule credits an incident to whoever was on call in the
+	// hour it was created.
+	attributeSchedule = "schedule"
+)
+
+// hourly holds incidents by UTC date and hour.
+type hourly map[string]map[int][]oncall.Incident
+
+func (h hourly) add(t time.Time, incident oncall.Incident) {
+	t = t.UTC()
+	date := t.Format(shortDate)
+	if _, ok := h[date]; !ok {
+		h[date] = map[int][]oncall.Incident{}
+	}
+	h[date][t.Hour()] = append(h[date][t.Hour()], incident)
+}
+
+func (h hourly) at(t time.Time) []oncall.Incident {
+	t = t.UTC()
+	return h[t.Format(shortDate)][t.Hour()]
+}
+
+// getResponses returns the incidents by the id of the users who
+// acknowledged or resolved them.
+func (p *pagerHours) getResponses() map[string]hourly {
+	responses := map[string]hourly{}
+	for _, incident := range p.policyIncidents {
+		responders := 0
+		for _, event := range p.events[incident.Id] {
+			if event.UserId == "" {
+				continue
+			}
+			if event.Type != oncall.EventAcknowledge && event.Type != oncall.EventResolve {
+				continue
+			}
+			if _, ok := responses[event.UserId]; !ok {
+				responses[event.UserId] = hourly{}
+			}
+			responses[event.UserId].add(event.At, incident)
+			responders++
+		}
+		if responders == 0 && p.attribution == attributeResponders {
+			log.Printf("-- incident %d was neither acknowledged nor resolved by a user", incident.Number)
+		}
+	}
+	return responses
+}
+
+// incidentsAt returns the incidents to credit to user for the given hour.
+func (p *pagerHours) incidentsAt(user worker, t time.Time) []oncall.Incident {
+	if p.attribution == attributeSchedule {
+		return p.incidents.at(t)
+	}
+	return p.responses[user.id].at(t)
+}
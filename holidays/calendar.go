@@ -0,0 +1,491 @@
+package holidays
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rule kinds of calendar files.
+const (
+	// KindFixed is a date every year, Date is "MM-DD" or a range
+	// "MM-DD..MM-DD".
+	KindFixed = "fixed"
+	// KindEaster is Offset days after Easter Sunday, Calendar selects
+	// "western" (default) or "orthodox" Easter.
+	KindEaster = "easter"
+	// KindNth is the N-th Weekday in Month, moved by Offset days.
+	KindNth = "nth"
+	// KindLast is the last Weekday in Month, moved by Offset days.
+	KindLast = "last"
+	// KindDates are dates which follow no rule, e.g. from lunar
+	// calendars. Years maps a year to its dates, "YYYY-MM-DD" or ranges
+	// "YYYY-MM-DD..YYYY-MM-DD" which may end in the next year. Holidays
+	// which are Recurring every year make Holiday fail for years without
+	// dates, other years without dates have no such holiday.
+	KindDates = "dates"
+)
+
+// Observance rules, which move holidays falling on a weekend to the day
+// taken off instead.
+const (
+	// ObserveNearestWeekday moves Saturdays to Friday and Sundays to Monday,
+	// like US federal holidays.
+	ObserveNearestWeekday = "nearest-weekday"
+	// ObserveNextWorkday moves Saturdays and Sundays to the next weekday
+	// which isn't a holiday itself, like substitute days in Bulgaria or the
+	// UK.
+	ObserveNextWorkday = "next-workday"
+)
+
+const dateLayout = "2006-01-02"
+
+//go:embed calendars/*.json
+var embedded embed.FS
+
+var (
+	mu        sync.RWMutex
+	calendars = map[Region]*calendar{}
+)
+
+// CalendarFile is the format of calendar files.
+type CalendarFile struct {
+	Region Region `json:"region"`
+	// Include are regions whose holidays apply as well.
+	Include  []Region   `json:"include"`
+	Holidays []RuleFile `json:"holidays"`
+}
+
+// RuleFile defines a holiday, which fields are used depends on Kind.
+type RuleFile struct {
+	Name     string              `json:"name"`
+	Kind     string              `json:"kind"`
+	Date     string              `json:"date,omitempty"`
+	Calendar string              `json:"calendar,omitempty"`
+	N        int                 `json:"n,omitempty"`
+	Weekday  string              `json:"weekday,omitempty"`
+	Month    string              `json:"month,omitempty"`
+	Offset   int                 `json:"offset,omitempty"`
+	Years    map[string][]string `json:"years,omitempty"`
+	// Observed is the observance rule, by default the holiday is observed
+	// on its date.
+	Observed string `json:"observed,omitempty"`
+	// Since is the first year of the holiday, it's always been one if 0.
+	Since int `json:"since,omitempty"`
+	// Recurring marks dates holidays which take place every year.
+	Recurring bool `json:"recurring,omitempty"`
+}
+
+type calendar struct {
+	region   Region
+	include  []Region
+	holidays []rule
+}
+
+// rule returns the dates of a holiday in a year.
+type rule struct {
+	name    string
+	observe string
+	dates   func(year int) []time.Time
+	// known reports whether the dates of year are known, it's nil for
+	// holidays following a rule.
+	known func(year int) bool
+}
+
+// instance is a holiday in a year.
+type instance struct {
+	name     string
+	date     time.Time
+	observed time.Time
+}
+
+func init() {
+	files, err := embedded.ReadDir("calendars")
+	if err != nil {
+		panic(err)
+	}
+	// Calendars may include ones loaded after them, so includes are checked
+	// once all are loaded.
+	for _, file := range files {
+		content, err := embedded.ReadFile(path.Join("calendars", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+		c, err := parse(content)
+		if err != nil {
+			panic(fmt.Sprintf("calendars/%s: %s", file.Name(), err))
+		}
+		calendars[c.region] = c
+	}
+	for _, c := range calendars {
+		if err := checkIncludes(c, nil); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Load parses a calendar and makes its region available, replacing a
+// calendar of the same region. The regions it includes must be available.
+func Load(content []byte) (Region, error) {
+	c, err := parse(content)
+	if err != nil {
+		return "", err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	previous, replaced := calendars[c.region]
+	calendars[c.region] = c
+	if err := checkIncludes(c, nil); err != nil {
+		if replaced {
+			calendars[c.region] = previous
+		} else {
+			delete(calendars, c.region)
+		}
+		return "", err
+	}
+	return c.region, nil
+}
+
+func parse(content []byte) (*calendar, error) {
+	var file CalendarFile
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("Couldn't parse calendar: %w", err)
+	}
+	if file.Region == "" {
+		return nil, fmt.Errorf("Calendar has no region")
+	}
+	c := &calendar{region: file.Region, include: file.Include}
+	for _, r := range file.Holidays {
+		compiled, err := r.compile()
+		if err != nil {
+			return nil, fmt.Errorf("Holiday %q: %w", r.Name, err)
+		}
+		c.holidays = append(c.holidays, compiled)
+	}
+	return c, nil
+}
+
+// checkIncludes makes sure the regions c includes, directly or through the
+// calendars of other regions, are available and don't include c again.
+// Included are the regions on the way to c. It must be called with mu held.
+func checkIncludes(c *calendar, included []Region) error {
+	for _, region := range included {
+		if region == c.region {
+			return fmt.Errorf("Region %s includes itself: %s", c.region, joinRegions(append(included, c.region)))
+		}
+	}
+	included = append(included, c.region)
+	for _, region := range c.include {
+		next, ok := calendars[region]
+		if !ok {
+			return fmt.Errorf("Region %s included by %s not supported", region, c.region)
+		}
+		if err := checkIncludes(next, included); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func joinRegions(regions []Region) string {
+	names := make([]string, len(regions))
+	for i, region := range regions {
+		names[i] = string(region)
+	}
+	return strings.Join(names, " -> ")
+}
+
+func (r RuleFile) compile() (rule, error) {
+	if r.Name == "" {
+		return rule{}, fmt.Errorf("No name")
+	}
+	compiled := rule{name: r.Name, observe: r.Observed}
+	switch r.Observed {
+	case "", ObserveNearestWeekday, ObserveNextWorkday:
+	default:
+		return rule{}, fmt.Errorf("Unknown observance %q", r.Observed)
+	}
+	switch r.Kind {
+	case KindFixed:
+		first, last, isRange := strings.Cut(r.Date, "..")
+		if !isRange {
+			last = first
+		}
+		from, err := time.Parse("01-02", first)
+		if err != nil {
+			return rule{}, fmt.Errorf("Invalid date %q, use MM-DD or MM-DD..MM-DD", r.Date)
+		}
+		to, err := time.Parse("01-02", last)
+		if err != nil || to.Before(from) {
+			return rule{}, fmt.Errorf("Invalid date %q, use MM-DD or MM-DD..MM-DD", r.Date)
+		}
+		compiled.dates = func(year int) []time.Time {
+			days := []time.Time{}
+			for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+				days = append(days, time.Date(year, day.Month(), day.Day(), 0, 0, 0, 0, time.UTC))
+			}
+			return days
+		}
+	case KindEaster:
+		easter := func(year int) time.Time {
+			return Easter(time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC))
+		}
+		switch r.Calendar {
+		case "", "western":
+		case "orthodox":
+			easter = OrthodoxEaster
+		default:
+			return rule{}, fmt.Errorf("Unknown Easter calendar %q", r.Calendar)
+		}
+		compiled.dates = func(year int) []time.Time {
+			return []time.Time{easter(year).AddDate(0, 0, r.Offset)}
+		}
+	case KindNth, KindLast:
+		weekday, err := parseWeekday(r.Weekday)
+		if err != nil {
+			return rule{}, err
+		}
+		month, err := parseMonth(r.Month)
+		if err != nil {
+			return rule{}, err
+		}
+		if r.Kind == KindNth && (r.N < 1 || r.N > 5) {
+			return rule{}, fmt.Errorf("Invalid n %d, use 1 to 5", r.N)
+		}
+		compiled.dates = func(year int) []time.Time {
+			var day time.Time
+			if r.Kind == KindNth {
+				day = time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+				day = day.AddDate(0, 0, (int(weekday)-int(day.Weekday())+7)%7+7*(r.N-1))
+				if day.Month() != month {
+					return nil
+				}
+			} else {
+				day = time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+				day = day.AddDate(0, 0, -((int(day.Weekday()) - int(weekday) + 7) % 7))
+			}
+			return []time.Time{day.AddDate(0, 0, r.Offset)}
+		}
+	case KindDates:
+		byYear := map[int][]time.Time{}
+		listed := map[int]bool{}
+		for y, dates := range r.Years {
+			year, err := strconv.Atoi(y)
+			if err != nil {
+				return rule{}, fmt.Errorf("Invalid year %q", y)
+			}
+			listed[year] = true
+			for _, d := range dates {
+				days, err := parseRange(d)
+				if err != nil {
+					return rule{}, err
+				}
+				for _, day := range days {
+					if day.Year() != year && day.Year() != year+1 {
+						return rule{}, fmt.Errorf("Date %s isn't in %d", day.Format(dateLayout), year)
+					}
+					// Ranges may reach into the next year.
+					byYear[day.Year()] = append(byYear[day.Year()], day)
+				}
+			}
+		}
+		for _, days := range byYear {
+			sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+		}
+		compiled.dates = func(year int) []time.Time {
+			return byYear[year]
+		}
+		if r.Recurring {
+			compiled.known = func(year int) bool {
+				return listed[year]
+			}
+		}
+	default:
+		return rule{}, fmt.Errorf("Unknown kind %q", r.Kind)
+	}
+	if r.Since != 0 {
+		dates := compiled.dates
+		compiled.dates = func(year int) []time.Time {
+			if year < r.Since {
+				return nil
+			}
+			return dates(year)
+		}
+		if known := compiled.known; known != nil {
+			compiled.known = func(year int) bool {
+				return year < r.Since || known(year)
+			}
+		}
+	}
+	return compiled, nil
+}
+
+// parseRange returns the days of "YYYY-MM-DD" or "YYYY-MM-DD..YYYY-MM-DD".
+func parseRange(s string) ([]time.Time, error) {
+	first, last, isRange := strings.Cut(s, "..")
+	if !isRange {
+		last = first
+	}
+	from, err := time.Parse(dateLayout, first)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid date %q, use YYYY-MM-DD", first)
+	}
+	to, err := time.Parse(dateLayout, last)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid date %q, use YYYY-MM-DD", last)
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("Range %q ends before it starts", s)
+	}
+	days := []time.Time{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), s) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("Invalid weekday %q", s)
+}
+
+func parseMonth(s string) (time.Month, error) {
+	for m := time.January; m <= time.December; m++ {
+		if strings.EqualFold(m.String(), s) {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("Invalid month %q", s)
+}
+
+// holiday returns the holiday at day in region or one of the regions it
+// includes.
+func (c *calendar) holiday(day time.Time) (holiday, error) {
+	instances, err := c.instances(day.Year())
+	if err != nil {
+		return holiday{}, err
+	}
+	for _, i := range instances {
+		actual, observed := i.date.Equal(day), i.observed.Equal(day)
+		if actual || observed {
+			return holiday{Name: i.name, Actual: actual, Observed: observed}, nil
+		}
+	}
+	for _, region := range c.include {
+		included, ok := lookup(region)
+		if !ok {
+			return holiday{}, fmt.Errorf("Region %s included by %s not supported", region, c.region)
+		}
+		h, err := included.holiday(day)
+		if err != NoHoliday {
+			return h, err
+		}
+	}
+	return holiday{}, NoHoliday
+}
+
+// instances returns the holidays around year and the days they are
+// observed. Holidays may be observed in the previous or next year, so it
+// covers the years before and after as well. It fails if the dates of a
+// holiday in year aren't known.
+func (c *calendar) instances(year int) ([]instance, error) {
+	for _, r := range c.holidays {
+		if r.known != nil && !r.known(year) {
+			return nil, fmt.Errorf("Dates of %s in %d unknown, add them to the calendar of %s", r.name, year, c.region)
+		}
+	}
+	years := []int{year - 1, year, year + 1}
+	// Substitute days skip holidays.
+	taken := map[time.Time]bool{}
+	for _, y := range years {
+		for _, r := range c.holidays {
+			for _, date := range r.dates(y) {
+				taken[date] = true
+			}
+		}
+	}
+	// substitutes are the substitute days by date, holidays on the same day
+	// share one.
+	substitutes := map[time.Time]time.Time{}
+	instances := []instance{}
+	for _, y := range years {
+		for _, r := range c.holidays {
+			// Consecutive days of a holiday, like a festival, are one
+			// holiday getting one substitute day after it.
+			for _, run := range consecutive(r.dates(y)) {
+				var substitute time.Time
+				if r.observe == ObserveNextWorkday {
+					for _, date := range run {
+						if s, ok := substitutes[date]; ok {
+							substitute = s
+						}
+					}
+				}
+				for _, date := range run {
+					observed := date
+					switch weekday := date.Weekday(); {
+					case r.observe == ObserveNearestWeekday && weekday == time.Saturday:
+						observed = date.AddDate(0, 0, -1)
+					case r.observe == ObserveNearestWeekday && weekday == time.Sunday:
+						observed = date.AddDate(0, 0, 1)
+					case r.observe == ObserveNextWorkday && isWeekend(date):
+						if substitute.IsZero() {
+							for substitute = run[len(run)-1].AddDate(0, 0, 1); isWeekend(substitute) || taken[substitute]; {
+								substitute = substitute.AddDate(0, 0, 1)
+							}
+							taken[substitute] = true
+						}
+						substitutes[date] = substitute
+						observed = substitute
+					}
+					instances = append(instances, instance{name: r.name, date: date, observed: observed})
+				}
+			}
+		}
+	}
+	return instances, nil
+}
+
+// consecutive splits dates into runs of consecutive days.
+func consecutive(dates []time.Time) [][]time.Time {
+	runs := [][]time.Time{}
+	for _, date := range dates {
+		if n := len(runs); n > 0 && runs[n-1][len(runs[n-1])-1].AddDate(0, 0, 1).Equal(date) {
+			runs[n-1] = append(runs[n-1], date)
+			continue
+		}
+		runs = append(runs, []time.Time{date})
+	}
+	return runs
+}
+
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
+
+// Supported reports whether there is a calendar for region.
+func Supported(region Region) bool {
+	_, ok := lookup(region)
+	return ok
+}
+
+func lookup(region Region) (*calendar, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := calendars[region]
+	return c, ok
+}
@@ -2,7 +2,6 @@ package holidays
 
 import (
 	"errors"
-	"fmt"
 	"time"
 )
 
@@ -10,231 +9,54 @@ type Region string
 
 const (
 	Bangkok    Region = "Bangkok"
-	Berlin     Region = "Berlin"
 	Bulgaria   Region = "Bulgaria"
 	California Region = "California"
 	NewYork    Region = "New York"
+	// USA are the federal holidays, which California and New York include.
+	USA Region = "USA"
 )
 
-var (
-	NoHoliday      = errors.New("No holiday")
-	orthodoxEaster = map[int]time.Time{
-		2013: time.Date(2013, 5, 5, 0, 0, 0, 0, time.UTC),
-		2014: time.Date(2013, 4, 20, 0, 0, 0, 0, time.UTC),
-		2015: time.Date(2013, 4, 12, 0, 0, 0, 0, time.UTC),
-		2016: time.Date(2013, 5, 1, 0, 0, 0, 0, time.UTC),
-		2017: time.Date(2013, 4, 16, 0, 0, 0, 0, time.UTC),
-		2018: time.Date(2013, 4, 8, 0, 0, 0, 0, time.UTC),
-	}
+// Germany are the national holidays, which the states (ISO 3166-2 codes)
+// include.
+const (
+	Germany               Region = "DE"
+	BadenWuerttemberg     Region = "DE-BW"
+	Bavaria               Region = "DE-BY"
+	Berlin                Region = "DE-BE"
+	Brandenburg           Region = "DE-BB"
+	Bremen                Region = "DE-HB"
+	Hamburg               Region = "DE-HH"
+	Hesse                 Region = "DE-HE"
+	LowerSaxony           Region = "DE-NI"
+	MecklenburgVorpommern Region = "DE-MV"
+	NorthRhineWestphalia  Region = "DE-NW"
+	RhinelandPalatinate   Region = "DE-RP"
+	Saarland              Region = "DE-SL"
+	Saxony                Region = "DE-SN"
+	SaxonyAnhalt          Region = "DE-ST"
+	SchleswigHolstein     Region = "DE-SH"
+	Thuringia             Region = "DE-TH"
 )
 
+var NoHoliday = errors.New("No holiday")
+
 type holiday struct {
 	Name string
+	// Actual is set on the date of the holiday.
+	Actual bool
+	// Observed is set on the day the holiday is taken off. That's its date
+	// unless the region moves holidays falling on a weekend.
+	Observed bool
 }
 
+// Holiday returns the holiday at the day of t, in the location of t. It
+// returns NoHoliday if the day isn't one in region.
 func Holiday(t time.Time, r Region) (holiday, error) {
-	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
-
-	switch r {
-	case Bangkok:
-		return holidayBangkok(day)
-	case Berlin:
-		return holidayBerlin(day)
-	case Bulgaria:
-		return holidayBulgaria(day)
-	case California:
-		return holidayUSA(day)
-	case NewYork:
-		return holidayUSA(day)
-	}
-	return holiday{}, errors.New("Region not supported")
-}
-
-func holidayBangkok(t time.Time) (holiday, error) {
-	return holiday{}, NoHoliday
-}
-
-func holidayBerlin(t time.Time) (holiday, error) {
-	// fixed
-	if t.Day() == 1 && t.Month() == time.January {
-		return holiday{Name: "New Year's Day"}, nil
-	}
-
-	if t.Day() == 1 && t.Month() == time.May {
-		return holiday{Name: "Labour Day"}, nil
-	}
-
-	if t.Day() == 3 && t.Month() == time.October {
-		return holiday{Name: "German Unity Day"}, nil
-	}
-
-	if t.Day() == 25 && t.Month() == time.December {
-		return holiday{Name: "Christmas Day"}, nil
-	}
-
-	if t.Day() == 26 && t.Month() == time.December {
-		return holiday{Name: "St. Stephen's Day"}, nil
-	}
-
-	// dynamic
-	eastern := Easter(t)
-
-	if t.Equal(eastern) {
-		return holiday{Name: "Easter"}, nil
-	}
-
-	if t.Equal(eastern.AddDate(0, 0, -2)) {
-		return holiday{Name: "Good Friday"}, nil
-	}
-
-	if t.Equal(eastern.AddDate(0, 0, 1)) {
-		return holiday{Name: "Easter Monday"}, nil
-	}
-
-	if t.Equal(eastern.AddDate(0, 0, 39)) {
-		return holiday{Name: "Ascension Day"}, nil
-	}
-
-	if t.Equal(eastern.AddDate(0, 0, 50)) {
-		return holiday{Name: "Whit Monday"}, nil
-	}
-
-	return holiday{}, NoHoliday
-}
-
-func holidayUSA(t time.Time) (holiday, error) {
-	// US-wide
-	if t.Day() == 1 && t.Month() == time.January {
-		return holiday{Name: "New Year's Day"}, nil
-	}
-
-	if t.Day() == 4 && t.Month() == time.July {
-		return holiday{Name: "Independence Day"}, nil
-	}
-
-	if t.Day() == 25 && t.Month() == time.December {
-		return holiday{Name: "Christmas Day"}, nil
-	}
-
-	// US-wide/dynamic
-	if t.Weekday() == time.Monday && t.Month() == time.September && nthDay(t) == 1 {
-		return holiday{Name: "Labor Day"}, nil
-	}
-
-	if t.Weekday() == time.Thursday && t.Month() == time.November && nthDay(t) == 4 {
-		return holiday{Name: "Thanksgiving Day"}, nil
-	}
-	if t.Weekday() == time.Friday && t.Month() == time.November && nthDay(t.AddDate(0, 0, -1)) == 4 { // "Friday after 4th Thursday in November"
-		return holiday{Name: "Day after Thanksgiving"}, nil
-	}
-
-	if t.Weekday() == time.Monday && t.Month() == time.May && nthDayRev(t) == 1 {
-		return holiday{Name: "Memorial Day"}, nil
-	}
-
-	if t.Weekday() == time.Monday && t.Month() == time.January && nthDay(t) == 3 {
-		return holiday{Name: "Martin Luther King Jr. Day"}, nil
-	}
-
-	return holiday{}, NoHoliday
-}
-
-func holidayBulgaria(t time.Time) (holiday, error) {
-	easter, ok := orthodoxEaster[t.Year()]
+	c, ok := lookup(r)
 	if !ok {
-		return holiday{}, fmt.Errorf("Don't know orthodox easter for year %d", t.Year())
-	}
-
-	if t.Equal(easter) {
-		return holiday{Name: "Easter"}, nil
-	}
-
-	if t.Equal(easter.AddDate(0, 0, -2)) {
-		return holiday{Name: "Good Friday"}, nil
-	}
-
-	if t.Equal(easter.AddDate(0, 0, -1)) {
-		return holiday{Name: "Easter Saturday"}, nil
-	}
-
-	if t.Equal(easter.AddDate(0, 0, 1)) {
-		return holiday{Name: "Easter Monday"}, nil
-	}
-
-	if t.Day() == 1 && t.Month() == time.January {
-		return holiday{Name: "New Year's Day"}, nil
-	}
-
-	if t.Day() == 2 && t.Month() == time.January {
-		return holiday{Name: "Day after New Year's Day"}, nil
-	}
-
-	if t.Day() == 3 && t.Month() == time.March {
-		return holiday{Name: "Liberation Day"}, nil
-	}
-
-	if t.Day() == 1 && t.Month() == time.May {
-		return holiday{Name: "Labour Day"}, nil
-	}
-
-	if t.Day() == 6 && t.Month() == time.May {
-		return holiday{Name: "St. George's Day"}, nil
-	}
-
-	if t.Day() == 24 && t.Month() == time.May {
-		return holiday{Name: "Bulgarian Education and Culture and Slavonic Literature Day"}, nil
+		return holiday{}, errors.New("Region not supported")
 	}
-
-	if t.Day() == 6 && t.Month() == time.September {
-		return holiday{Name: "Unification Day"}, nil
-	}
-
-	if t.Day() == 22 && t.Month() == time.September {
-		return holiday{Name: "Independence Day"}, nil
-	}
-
-	if t.Day() == 1 && t.Month() == time.November {
-		return holiday{Name: "Day of the Bulgarian Enlighteners"}, nil
-	}
-
-	if t.Day() == 24 && t.Month() == time.December {
-		return holiday{Name: "Christmas Eve"}, nil
-	}
-
-	if t.Day() == 25 && t.Month() == time.December {
-		return holiday{Name: "Christmas Day"}, nil
-	}
-
-	if t.Day() == 26 && t.Month() == time.December {
-		return holiday{Name: "Second Day of Christmas"}, nil
-	}
-
-	return holiday{}, NoHoliday
-}
-
-//returns the number of the weekday in a month (is it the 1th, 2th.. Monday)
-func nthDay(t time.Time) (n int) {
-	cDay := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
-
-	for cDay.Before(t) || cDay.Equal(t) {
-		if cDay.Weekday() == t.Weekday() {
-			n++
-		}
-		cDay = cDay.AddDate(0, 0, 1)
-	}
-	return
-}
-
-func nthDayRev(t time.Time) (n int) {
-	cDay := time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location()).AddDate(0, 0, -1) // last day in Month
-
-	for cDay.After(t) || cDay.Equal(t) {
-		if cDay.Weekday() == t.Weekday() {
-			n++
-		}
-		cDay = cDay.AddDate(0, 0, -1)
-	}
-	return
+	return c.holiday(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC))
 }
 
 // -- http://rosettacode.org/wiki/Holidays_related_to_Easter#Goa
@@ -259,3 +81,19 @@ func Easter(t time.Time) time.Time {
 }
 
 // --
+
+// OrthodoxEaster returns orthodox Easter Sunday of year in the Gregorian
+// calendar. It's computed in the Julian calendar (Meeus' algorithm) and
+// converted, which is valid from March 1900 on.
+func OrthodoxEaster(year int) time.Time {
+	a := year % 4
+	b := year % 7
+	c := year % 19
+	d := (19*c + 15) % 30
+	e := (2*a + 4*b - d + 34) % 7
+	month := (d + e + 114) / 31
+	day := (d+e+114)%31 + 1
+	// Days the Julian calendar lags behind the Gregorian one.
+	lag := year/100 - year/400 - 2
+	return time.Date(year, time.Month(month), day+lag, 0, 0, 0, 0, time.UTC)
+}
@@ -39,6 +39,71 @@ func TestHolidayAllBulgaria(t *testing.T) {
 	}
 }
 
+func TestHolidayAllGermanStates(t *testing.T) {
+	for _, tc := range []struct {
+		region holidays.Region
+		file   string
+		from   int
+		to     int
+		count  int
+	}{
+		{holidays.Berlin, "test/fixtures/holidays_berlin.csv", 2025, 2025, 11},
+		{holidays.Bavaria, "test/fixtures/holidays_bavaria.csv", 2024, 2024, 13},
+		{holidays.Hamburg, "test/fixtures/holidays_hamburg.csv", 2017, 2018, 20},
+		{holidays.Saxony, "test/fixtures/holidays_saxony.csv", 2024, 2024, 11},
+		{holidays.Hamburg, "", 2016, 2016, 9},
+		{holidays.Berlin, "", 2018, 2018, 9},
+	} {
+		if tc.file != "" {
+			if err := compareToFixtures(tc.region, tc.file); err != nil {
+				t.Errorf("%s: %s", tc.region, err)
+			}
+		}
+		from := time.Date(tc.from, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(tc.to+1, 1, 1, 0, 0, 0, 0, time.UTC)
+		if n := countHolidays(tc.region, from, to); n != tc.count {
+			t.Errorf("Expected %d holidays in %s %d-%d, got %d", tc.count, tc.region, tc.from, tc.to, n)
+		}
+	}
+}
+
+func TestHolidayAllBangkok(t *testing.T) {
+	if err := compareToFixtures(holidays.Bangkok, "test/fixtures/holidays_bangkok.csv"); err != nil {
+		t.Fatalf("Failed: %s", err)
+	}
+	// The fixtures list every holiday and substitute day of these years.
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2028, 1, 1, 0, 0, 0, 0, time.UTC)
+	if n := countHolidays(holidays.Bangkok, from, to); n != 119 {
+		t.Fatalf("Expected 119 holidays in %d-%d, got %d", from.Year(), to.Year()-1, n)
+	}
+}
+
+func TestUnknownLunarDates(t *testing.T) {
+	for _, day := range []time.Time{
+		time.Date(2028, 1, 4, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC),
+	} {
+		if holiday, err := holidays.Holiday(day, holidays.Bangkok); err == nil || err == holidays.NoHoliday {
+			t.Errorf("Expected error for %s in Bangkok without lunar dates, got %+v, %v", day, holiday, err)
+		}
+	}
+	// One-off holidays just don't take place in other years.
+	day := time.Date(2021, 5, 8, 0, 0, 0, 0, time.UTC)
+	if _, err := holidays.Holiday(day, holidays.Berlin); err != holidays.NoHoliday {
+		t.Errorf("Expected no holiday for %s in Berlin, got %v", day, err)
+	}
+}
+
+func countHolidays(region holidays.Region, from, to time.Time) (n int) {
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		if _, err := holidays.Holiday(day, region); err == nil {
+			n++
+		}
+	}
+	return n
+}
+
 func compareToFixtures(region holidays.Region, file string) error {
 	fd, err := os.Open(file)
 	if err != nil {
@@ -77,7 +142,151 @@ func TestMemorialDayPST(t *testing.T) {
 	}
 	holiday, err := holidays.Holiday(dt, holidays.California)
 	if err == nil {
-		t.Logf("%s isn't a holiday but library says it's %s", dt, holiday)
+		t.Logf("%s isn't a holiday but library says it's %s", dt, holiday.Name)
 		t.FailNow()
 	}
 }
+
+func TestLoad(t *testing.T) {
+	region, err := holidays.Load([]byte(`{
+  "region": "Test",
+  "include": ["USA"],
+  "holidays": [
+    {"name": "Company Day", "kind": "nth", "n": 2, "weekday": "Friday", "month": "June", "offset": 3},
+    {"name": "Shutdown", "kind": "dates", "years": {"2023": ["2023-12-27..2024-01-02"]}}
+  ]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for date, name := range map[string]string{
+		"2023-06-12": "Company Day",
+		"2023-12-27": "Shutdown",
+		"2024-01-01": "Shutdown",
+		"2024-01-02": "Shutdown",
+		"2023-07-04": "Independence Day",
+		"2023-12-26": "",
+		"2024-01-03": "",
+	} {
+		day, _ := time.Parse("2006-01-02", date)
+		holiday, err := holidays.Holiday(day, region)
+		if name == "" {
+			if err != holidays.NoHoliday {
+				t.Errorf("%s: expected no holiday, got %v, %v", date, holiday, err)
+			}
+			continue
+		}
+		if err != nil || holiday.Name != name {
+			t.Errorf("%s: expected %s, got %v, %v", date, name, holiday, err)
+		}
+	}
+
+	for _, invalid := range []string{
+		`{"holidays": []}`,
+		`{"region": "Test", "holidays": [{"name": "Foo", "kind": "weekly"}]}`,
+		`{"region": "Test", "holidays": [{"name": "Foo", "kind": "fixed", "date": "2023-01-01"}]}`,
+		`{"region": "Test", "holidays": [{"name": "Foo", "kind": "fixed", "date": "04-15..04-13"}]}`,
+		`{"region": "Test", "holidays": [{"name": "Foo", "kind": "nth", "n": 6, "weekday": "Monday", "month": "May"}]}`,
+		`{"region": "Test", "holidays": [{"name": "Foo", "kind": "dates", "years": {"2023": ["2022-01-01"]}}]}`,
+	} {
+		if _, err := holidays.Load([]byte(invalid)); err == nil {
+			t.Errorf("Expected error loading %s", invalid)
+		}
+	}
+}
+
+func TestLoadIncludes(t *testing.T) {
+	for _, invalid := range []string{
+		`{"region": "Unknown", "include": ["Nowhere"], "holidays": []}`,
+		`{"region": "Itself", "include": ["Itself"], "holidays": []}`,
+	} {
+		if _, err := holidays.Load([]byte(invalid)); err == nil {
+			t.Errorf("Expected error loading %s", invalid)
+		}
+	}
+	if holidays.Supported("Unknown") || holidays.Supported("Itself") {
+		t.Error("Expected invalid calendars not to be available")
+	}
+
+	// Replacing a calendar mustn't make calendars include each other.
+	for _, calendar := range []string{
+		`{"region": "A", "holidays": [{"name": "A Day", "kind": "fixed", "date": "03-01"}]}`,
+		`{"region": "B", "include": ["A"], "holidays": []}`,
+	} {
+		if _, err := holidays.Load([]byte(calendar)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := holidays.Load([]byte(`{"region": "A", "include": ["B"], "holidays": []}`)); err == nil {
+		t.Fatal("Expected error loading calendars including each other")
+	}
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	if holiday, err := holidays.Holiday(day, "B"); err != nil || holiday.Name != "A Day" {
+		t.Errorf("Expected A Day from the previous calendar of A, got %+v, %v", holiday, err)
+	}
+}
+
+func TestOrthodoxEaster(t *testing.T) {
+	for year, date := range map[int]string{
+		2013: "2013-05-05",
+		2014: "2014-04-20",
+		2015: "2015-04-12",
+		2016: "2016-05-01",
+		2017: "2017-04-16",
+		2018: "2018-04-08",
+		2019: "2019-04-28",
+		2020: "2020-04-19",
+		2021: "2021-05-02",
+		2022: "2022-04-24",
+		2023: "2023-04-16",
+		2024: "2024-05-05",
+		2025: "2025-04-20",
+		2100: "2100-05-02",
+	} {
+		if got := holidays.OrthodoxEaster(year).Format("2006-01-02"); got != date {
+			t.Errorf("Expected orthodox Easter %d on %s, got %s", year, date, got)
+		}
+	}
+
+	day := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+	holiday, err := holidays.Holiday(day, holidays.Bulgaria)
+	if err != nil || holiday.Name != "Good Friday" {
+		t.Errorf("Expected Good Friday on %s in Bulgaria, got %v, %v", day, holiday, err)
+	}
+}
+
+func TestObserved(t *testing.T) {
+	for _, tc := range []struct {
+		region   holidays.Region
+		date     string
+		name     string
+		actual   bool
+		observed bool
+	}{
+		{holidays.NewYork, "2021-12-31", "New Year's Day", false, true},
+		{holidays.NewYork, "2022-01-01", "New Year's Day", true, false},
+		{holidays.California, "2021-07-04", "Independence Day", true, false},
+		{holidays.California, "2021-07-05", "Independence Day", false, true},
+		{holidays.California, "2023-07-04", "Independence Day", true, true},
+		{holidays.Bulgaria, "2013-09-23", "Independence Day", false, true},
+		{holidays.Bulgaria, "2021-12-25", "Christmas Day", true, false},
+		{holidays.Bulgaria, "2021-12-27", "Christmas Day", false, true},
+		{holidays.Bulgaria, "2021-12-28", "Second Day of Christmas", false, true},
+		{holidays.Berlin, "2021-12-26", "St. Stephen's Day", true, true},
+	} {
+		day, _ := time.Parse("2006-01-02", tc.date)
+		holiday, err := holidays.Holiday(day, tc.region)
+		if err != nil {
+			t.Errorf("%s in %s: %s", tc.date, tc.region, err)
+			continue
+		}
+		if holiday.Name != tc.name || holiday.Actual != tc.actual || holiday.Observed != tc.observed {
+			t.Errorf("%s in %s: expected %s (actual %t, observed %t), got %+v", tc.date, tc.region, tc.name, tc.actual, tc.observed, holiday)
+		}
+	}
+
+	day := time.Date(2021, 12, 27, 0, 0, 0, 0, time.UTC)
+	if holiday, err := holidays.Holiday(day, holidays.Berlin); err != holidays.NoHoliday {
+		t.Errorf("Expected no substitute day for %s in Berlin, got %+v, %v", day, holiday, err)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/discordianfish/pager-hours/oncall"
+)
+
+var metricsHeaders = []string{
+	"User",
+	"Type",
+	"Time",
+	"Incidents Acknowledged",
+	"Mean Time to Acknowledge (min)",
+	"Median Time to Acknowledge (min)",
+	"Incidents Resolved",
+	"Mean Time to Resolve (min)",
+	"Median Time to Resolve (min)",
+}
+
+type responseKey struct {
+	user   worker
+	bucket string
+	time   string // day or night
+}
+
+type responseTimes struct {
+	acknowledge []time.Duration
+	resolve     []time.Duration
+}
+
+// responseKeyFor returns the key of an incident created at t and handled by
+// the user with the given id, bucketed in the user's local time.
+func (p *pagerHours) responseKeyFor(id string, t time.Time) (responseKey, error) {
+	user := p.lookupUser(id)
+	local := t.In(user.location)
+	bucket, err := bucketFor(local, user)
+	if err != nil {
+		return responseKey{}, err
+	}
+	key := responseKey{user: user, bucket: bucket, time: day}
+	if local.Hour() >= nightStart && local.Hour() < nightEnd {
+		key.time = night
+	}
+	return key, nil
+}
+
+// getResponseTimes credits the time to acknowledge to whoever acknowledged an
+// incident first and the time to resolve to whoever resolved it.
+func (p *pagerHours) getResponseTimes() (map[responseKey]*responseTimes, error) {
+	times := map[responseKey]*responseTimes{}
+	get := func(key responseKey) *responseTimes {
+		if _, ok := times[key]; !ok {
+			times[key] = &responseTimes{}
+		}
+		return times[key]
+	}
+
+	for _, incident := range p.policyIncidents {
+		acknowledged := false
+		for _, event := range p.events[incident.Id] {
+			if event.UserId == "" {
+				continue
+			}
+			switch event.Type {
+			case oncall.EventAcknowledge:
+				if acknowledged {
+					continue
+				}
+				acknowledged = true
+				key, err := p.responseKeyFor(event.UserId, incident.CreatedAt)
+				if err != nil {
+					return nil, err
+				}
+				rt := get(key)
+				rt.acknowledge = append(rt.acknowledge, event.At.Sub(incident.CreatedAt))
+			case oncall.EventResolve:
+				key, err := p.responseKeyFor(event.UserId, incident.CreatedAt)
+				if err != nil {
+					return nil, err
+				}
+				rt := get(key)
+				rt.resolve = append(rt.resolve, event.At.Sub(incident.CreatedAt))
+			}
+		}
+	}
+	return times, nil
+}
+
+func (p *pagerHours) writeMetrics(file io.Writer) error {
+	times, err := p.getResponseTimes()
+	if err != nil {
+		return err
+	}
+	keys := make([]responseKey, 0, len(times))
+	for key := range times {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.user.email != b.user.email {
+			return a.user.email < b.user.email
+		}
+		if a.bucket != b.bucket {
+			return a.bucket < b.bucket
+		}
+		return a.time < b.time
+	})
+
+	csvw := csv.NewWriter(file)
+	csvw.Write(metricsHeaders)
+	for _, key := range keys {
+		rt := times[key]
+		csvw.Write([]string{
+			key.user.email,
+			key.bucket,
+			key.time,
+			strconv.Itoa(len(rt.acknowledge)),
+			minutes(mean(rt.acknowledge)),
+			minutes(median(rt.acknowledge)),
+			strconv.Itoa(len(rt.resolve)),
+			minutes(mean(rt.resolve)),
+			minutes(median(rt.resolve)),
+		})
+	}
+	csvw.Flush()
+	return csvw.Error()
+}
+
+func minutes(d time.Duration) string {
+	return fmt.Sprintf("%.1f", d.Minutes())
+}
+
+func mean(ds []time.Duration) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range ds {
+		sum += d
+	}
+	return sum / time.Duration(len(ds))
+}
+
+func median(ds []time.Duration) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -0,0 +1,137 @@
+// Package oncall defines what pager-hours needs from an on-call provider
+// like PagerDuty or Opsgenie, and the provider independent types it uses.
+package oncall
+
+import (
+	"context"
+	"time"
+)
+
+// Target types of escalation rules.
+const (
+	TargetSchedule = "schedule"
+	TargetUser     = "user"
+)
+
+// Event types in the lifecycle of an incident.
+const (
+	EventNotify      = "notify"
+	EventAcknowledge = "acknowledge"
+	EventResolve     = "resolve"
+	EventAssign      = "assign"
+)
+
+// Provider is implemented by every on-call backend.
+type Provider interface {
+	Policies(ctx context.Context) ([]Policy, error)
+	Policy(ctx context.Context, id string) (Policy, error)
+	// Shifts returns who was on call for a schedule between from and to.
+	Shifts(ctx context.Context, scheduleId string, from, to time.Time) ([]Shift, error)
+	// Incidents returns the incidents handled by policy created between
+	// from and to.
+	Incidents(ctx context.Context, policy Policy, from, to time.Time) ([]Incident, error)
+	// Events returns the lifecycle events of an incident, oldest first.
+	Events(ctx context.Context, incident Incident) ([]Event, error)
+	User(ctx context.Context, id string) (User, error)
+	// Users returns the members of the given teams.
+	Users(ctx context.Context, teamIds []string) ([]User, error)
+	// MaintenanceWindows returns the maintenance windows of the given
+	// services which overlap with the time between from and to.
+	MaintenanceWindows(ctx context.Context, serviceIds []string, from, to time.Time) ([]MaintenanceWindow, error)
+}
+
+type Reference struct {
+	Id   string
+	Type string
+	Name string
+}
+
+// Policy is an escalation policy. Rules are ordered by escalation, the first
+// rule is level 1.
+type Policy struct {
+	Id       string
+	Name     string
+	Services []Reference
+	Teams    []Reference
+	Rules    []Rule
+}
+
+type Rule struct {
+	Id      string
+	Targets []Reference
+}
+
+// Shift is a continuous time a user was on call.
+type Shift struct {
+	UserId string
+	Start  time.Time
+	End    time.Time
+	// Layer names the schedule layer or rotation the shift comes from.
+	Layer string
+	// Override is set if the shift comes from a schedule override,
+	// OverriddenId is the user who would have been on call without it.
+	Override     bool
+	OverriddenId string
+}
+
+type User struct {
+	Id       string
+	Name     string
+	Email    string
+	Location *time.Location
+}
+
+type Incident struct {
+	Id        string
+	Number    int
+	Title     string
+	Status    string
+	CreatedAt time.Time
+	PolicyId  string
+	Service   Reference
+	// Maintenance is set if the incident was created during a maintenance
+	// window of its service.
+	Maintenance bool
+}
+
+// MaintenanceWindow is a planned maintenance of services, incidents created
+// during it aren't counted.
+type MaintenanceWindow struct {
+	Start    time.Time
+	End      time.Time
+	Services []Reference
+}
+
+// Event is something that happened to an incident. UserId is set if the
+// event was caused by (or, for notifications, sent to) a user.
+type Event struct {
+	Type   string
+	At     time.Time
+	UserId string
+}
+
+// IncidentSource provides incidents and their events, e.g. from a local
+// store instead of the provider's API.
+type IncidentSource interface {
+	Incidents(ctx context.Context, policy Policy, from, to time.Time) ([]Incident, error)
+	Events(ctx context.Context, incident Incident) ([]Event, error)
+}
+
+// WithIncidents returns a Provider which gets incidents and their events
+// from source, and everything else from provider.
+func WithIncidents(provider Provider, source IncidentSource) Provider {
+	return incidentProvider{Provider: provider, source: source}
+}
+
+type incidentProvider struct {
+	Provider
+	source IncidentSource
+}
+
+func (p incidentProvider) Incidents(ctx context.Context, policy Policy, from, to time.Time) ([]Incident, error) {
+	return p.source.Incidents(ctx, policy, from, to)
+}
+
+func (p incidentProvider) Events(ctx context.Context, incident Incident) ([]Event, error) {
+	return p.source.Events(ctx, incident)
+}
@@ -0,0 +1,54 @@
+package oncall
+
+import (
+	"context"
+	"sync"
+)
+
+// UserCache holds users by id. Users not loaded up front are fetched on
+// first use.
+type UserCache struct {
+	provider Provider
+	mu       sync.Mutex
+	users    map[string]User
+}
+
+func NewUserCache(provider Provider) *UserCache {
+	return &UserCache{
+		provider: provider,
+		users:    map[string]User{},
+	}
+}
+
+// Load adds all members of the given teams to the cache.
+func (c *UserCache) Load(ctx context.Context, teamIds []string) error {
+	users, err := c.provider.Users(ctx, teamIds)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, user := range users {
+		c.users[user.Id] = user
+	}
+	return nil
+}
+
+// Get is safe for concurrent use.
+func (c *UserCache) Get(ctx context.Context, id string) (User, error) {
+	c.mu.Lock()
+	user, ok := c.users[id]
+	c.mu.Unlock()
+	if ok {
+		return user, nil
+	}
+
+	user, err := c.provider.User(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+	c.mu.Lock()
+	c.users[id] = user
+	c.mu.Unlock()
+	return user, nil
+}
@@ -0,0 +1,151 @@
+// Package opsgenie implements oncall.Provider for the Opsgenie REST API v2.
+package opsgenie
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	apiUrl       = "https://api.opsgenie.com"
+	defaultLimit = 100
+)
+
+// APIError is returned for every request Opsgenie answers with a non-200
+// status.
+type APIError struct {
+	StatusCode int
+	Message    string
+	Path       string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s: status %d", e.Path, e.StatusCode)
+	}
+	return fmt.Sprintf("%s: status %d: %s", e.Path, e.StatusCode, e.Message)
+}
+
+// Temporary reports whether the request may succeed when retried later.
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+type Client struct {
+	token      string
+	url        string
+	httpClient *http.Client
+}
+
+// Option configures a Client, see New.
+type Option func(*Client)
+
+// WithBaseURL sets the API endpoint, e.g. https://api.eu.opsgenie.com.
+func WithBaseURL(url string) Option {
+	return func(og *Client) {
+		og.url = strings.TrimSuffix(url, "/")
+	}
+}
+
+// WithHTTPClient sets the http.Client used for requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(og *Client) {
+		og.httpClient = client
+	}
+}
+
+func New(token string, options ...Option) *Client {
+	og := &Client{
+		token:      token,
+		url:        apiUrl,
+		httpClient: &http.Client{Timeout: time.Minute},
+	}
+	for _, option := range options {
+		option(og)
+	}
+	return og
+}
+
+func (og *Client) getBody(ctx context.Context, path string, params url.Values) ([]byte, error) {
+	url := fmt.Sprintf("%s/%s?%s", og.url, path, params.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "GenieKey "+og.token)
+	resp, err := og.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("getBody: %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("getBody: %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Path: path}
+		var response struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(body, &response); err == nil {
+			apiErr.Message = response.Message
+		}
+		return nil, apiErr
+	}
+	return body, nil
+}
+
+// get requests path and unmarshals the "data" field of the response into v.
+func (og *Client) get(ctx context.Context, path string, params url.Values, v interface{}) error {
+	body, err := og.getBody(ctx, path, params)
+	if err != nil {
+		return err
+	}
+	response := struct {
+		Data interface{} `json:"data"`
+	}{Data: v}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("Couldn't unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// list requests all pages of path and returns their "data".
+func list[T any](ctx context.Context, og *Client, path string, params url.Values) ([]T, error) {
+	items := []T{}
+	query := url.Values{}
+	for k, v := range params {
+		query[k] = v
+	}
+	query.Set("limit", strconv.Itoa(defaultLimit))
+
+	for offset := 0; ; {
+		query.Set("offset", strconv.Itoa(offset))
+		body, err := og.getBody(ctx, path, query)
+		if err != nil {
+			return nil, err
+		}
+		var response struct {
+			Data   []T `json:"data"`
+			Paging struct {
+				Next string `json:"next"`
+			} `json:"paging"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("Couldn't unmarshal response: %w", err)
+		}
+		items = append(items, response.Data...)
+		if response.Paging.Next == "" || len(response.Data) == 0 {
+			return items, nil
+		}
+		offset += len(response.Data)
+	}
+}
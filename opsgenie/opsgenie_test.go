@@ -0,0 +1,125 @@
+package opsgenie_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/discordianfish/pager-hours/oncall"
+	"github.com/discordianfish/pager-hours/opsgenie"
+)
+
+// newFakeOpsgenie serves the recorded responses in test/fixtures, ignoring
+// the query.
+func newFakeOpsgenie(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "GenieKey key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, err := os.ReadFile(filepath.Join("test/fixtures", r.URL.Path+".json"))
+		if err != nil {
+			t.Logf("No fixture for %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	}))
+}
+
+func TestPolicy(t *testing.T) {
+	ts := newFakeOpsgenie(t)
+	defer ts.Close()
+
+	og := opsgenie.New("key", opsgenie.WithBaseURL(ts.URL))
+	policy, err := og.Policy(context.Background(), "ESC1")
+	if err != nil {
+		t.Fatalf("Couldn't get policy: %s", err)
+	}
+	if len(policy.Rules) != 2 {
+		t.Fatalf("Expected 2 levels, got %d", len(policy.Rules))
+	}
+	if n := len(policy.Rules[0].Targets); n != 2 {
+		t.Fatalf("Expected 2 targets on level 1, got %d", n)
+	}
+	if target := policy.Rules[1].Targets[0]; target.Type != oncall.TargetUser || target.Id != "bob@example.com" {
+		t.Fatalf("Unexpected target on level 2: %#v", target)
+	}
+}
+
+func TestShifts(t *testing.T) {
+	ts := newFakeOpsgenie(t)
+	defer ts.Close()
+
+	og := opsgenie.New("key", opsgenie.WithBaseURL(ts.URL))
+	from := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	shifts, err := og.Shifts(context.Background(), "SCHED1", from, from.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("Couldn't get shifts: %s", err)
+	}
+	if len(shifts) != 3 {
+		t.Fatalf("Expected 3 shifts, got %d", len(shifts))
+	}
+	for _, shift := range shifts {
+		if shift.Layer != "daily" {
+			t.Fatalf("Expected shifts of rotation daily, got %q", shift.Layer)
+		}
+	}
+	override := shifts[1]
+	if !override.Override || override.UserId != "bob@example.com" || override.OverriddenId != "alice@example.com" {
+		t.Fatalf("Unexpected override: %#v", override)
+	}
+}
+
+func TestIncidents(t *testing.T) {
+	ts := newFakeOpsgenie(t)
+	defer ts.Close()
+
+	og := opsgenie.New("key", opsgenie.WithBaseURL(ts.URL))
+	ctx := context.Background()
+	policy, err := og.Policy(ctx, "ESC1")
+	if err != nil {
+		t.Fatalf("Couldn't get policy: %s", err)
+	}
+	from := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	incidents, err := og.Incidents(ctx, policy, from, from.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("Couldn't get incidents: %s", err)
+	}
+	if len(incidents) != 1 || incidents[0].Number != 11 {
+		t.Fatalf("Expected only alert 11, got %#v", incidents)
+	}
+
+	events, err := og.Events(ctx, incidents[0])
+	if err != nil {
+		t.Fatalf("Couldn't get events: %s", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	ack := events[0]
+	if ack.Type != oncall.EventAcknowledge || ack.UserId != "alice@example.com" || !ack.At.Equal(from.Add(8*time.Hour+15*time.Minute)) {
+		t.Fatalf("Unexpected acknowledgement: %#v", ack)
+	}
+	if events[1].UserId != "" {
+		t.Fatalf("Alert closed by integration must not be credited to a user: %#v", events[1])
+	}
+}
+
+func TestUsers(t *testing.T) {
+	ts := newFakeOpsgenie(t)
+	defer ts.Close()
+
+	og := opsgenie.New("key", opsgenie.WithBaseURL(ts.URL))
+	users, err := og.Users(context.Background(), []string{"TEAM1"})
+	if err != nil {
+		t.Fatalf("Couldn't get users: %s", err)
+	}
+	if len(users) != 1 || users[0].Id != "alice@example.com" || users[0].Location.String() != "Europe/Berlin" {
+		t.Fatalf("Unexpected users: %#v", users)
+	}
+}
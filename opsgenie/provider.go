@@ -0,0 +1,331 @@
+package opsgenie
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/discordianfish/pager-hours/oncall"
+)
+
+var _ oncall.Provider = &Client{}
+
+type recipient struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type escalation struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	OwnerTeam struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"ownerTeam"`
+	Rules []struct {
+		Delay struct {
+			TimeAmount int    `json:"timeAmount"`
+			TimeUnit   string `json:"timeUnit"`
+		} `json:"delay"`
+		Recipient recipient `json:"recipient"`
+	} `json:"rules"`
+}
+
+type period struct {
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+	Type      string    `json:"type"`
+	Recipient recipient `json:"recipient"`
+}
+
+type timeline struct {
+	Rotations []struct {
+		Id      string   `json:"id"`
+		Name    string   `json:"name"`
+		Periods []period `json:"periods"`
+	} `json:"rotations"`
+}
+
+type user struct {
+	Id       string `json:"id"`
+	Username string `json:"username"`
+	FullName string `json:"fullName"`
+	TimeZone string `json:"timeZone"`
+}
+
+type alert struct {
+	Id         string      `json:"id"`
+	TinyId     string      `json:"tinyId"`
+	Message    string      `json:"message"`
+	Status     string      `json:"status"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	Responders []recipient `json:"responders"`
+	Report     struct {
+		AckTime        int64  `json:"ackTime"`   // milliseconds after creation
+		CloseTime      int64  `json:"closeTime"` // milliseconds after creation
+		AcknowledgedBy string `json:"acknowledgedBy"`
+		ClosedBy       string `json:"closedBy"`
+	} `json:"report"`
+}
+
+func (og *Client) Policies(ctx context.Context) ([]oncall.Policy, error) {
+	var escalations []escalation
+	if err := og.get(ctx, "v2/escalations", nil, &escalations); err != nil {
+		return nil, fmt.Errorf("Couldn't request escalations: %w", err)
+	}
+	policies := []oncall.Policy{}
+	for _, e := range escalations {
+		policies = append(policies, e.toPolicy())
+	}
+	return policies, nil
+}
+
+func (og *Client) Policy(ctx context.Context, id string) (oncall.Policy, error) {
+	params := url.Values{}
+	params.Set("identifierType", "id")
+	var e escalation
+	if err := og.get(ctx, "v2/escalations/"+id, params, &e); err != nil {
+		return oncall.Policy{}, fmt.Errorf("Couldn't request escalation %s: %w", id, err)
+	}
+	return e.toPolicy(), nil
+}
+
+// toPolicy turns the escalation rules into levels. Rules with the same delay
+// notify at the same time and form one level.
+func (e escalation) toPolicy() oncall.Policy {
+	policy := oncall.Policy{Id: e.Id, Name: e.Name}
+	if e.OwnerTeam.Id != "" {
+		policy.Teams = []oncall.Reference{{Id: e.OwnerTeam.Id, Type: "team", Name: e.OwnerTeam.Name}}
+	}
+
+	levels := map[time.Duration]*oncall.Rule{}
+	delays := []time.Duration{}
+	for _, rule := range e.Rules {
+		delay := time.Duration(rule.Delay.TimeAmount) * unit(rule.Delay.TimeUnit)
+		if _, ok := levels[delay]; !ok {
+			levels[delay] = &oncall.Rule{Id: delay.String()}
+			delays = append(delays, delay)
+		}
+		target := oncall.Reference{Id: rule.Recipient.Id, Type: rule.Recipient.Type, Name: rule.Recipient.Name}
+		if target.Type == oncall.TargetUser {
+			// Users are identified by username, like in timelines and alerts.
+			target.Id = rule.Recipient.Name
+		}
+		levels[delay].Targets = append(levels[delay].Targets, target)
+	}
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+	for _, delay := range delays {
+		policy.Rules = append(policy.Rules, *levels[delay])
+	}
+	return policy
+}
+
+func unit(timeUnit string) time.Duration {
+	switch timeUnit {
+	case "hours":
+		return time.Hour
+	case "days":
+		return 24 * time.Hour
+	}
+	return time.Minute
+}
+
+// Shifts returns the final timeline of a schedule. Users are identified by
+// username.
+func (og *Client) Shifts(ctx context.Context, scheduleId string, from, to time.Time) ([]oncall.Shift, error) {
+	days := int(to.Sub(from).Hours()/24 + 0.5)
+	if days < 1 {
+		days = 1
+	}
+	params := url.Values{}
+	params.Set("identifierType", "id")
+	params.Set("expand", "base")
+	params.Set("date", from.UTC().Format(time.RFC3339))
+	params.Set("interval", fmt.Sprint(days))
+	params.Set("intervalUnit", "days")
+
+	var response struct {
+		FinalTimeline timeline `json:"finalTimeline"`
+		BaseTimeline  timeline `json:"baseTimeline"`
+	}
+	if err := og.get(ctx, fmt.Sprintf("v2/schedules/%s/timeline", scheduleId), params, &response); err != nil {
+		return nil, fmt.Errorf("Couldn't request timeline of schedule %s: %w", scheduleId, err)
+	}
+
+	shifts := []oncall.Shift{}
+	for _, rotation := range response.FinalTimeline.Rotations {
+		for _, p := range rotation.Periods {
+			if p.Recipient.Type != oncall.TargetUser {
+				continue
+			}
+			shift := oncall.Shift{UserId: p.Recipient.Name, Start: p.StartDate, End: p.EndDate, Layer: rotation.Name}
+			if p.Type == "override" {
+				shift.Override = true
+				shift.OverriddenId = response.BaseTimeline.userAt(p.StartDate)
+			}
+			shifts = append(shifts, shift)
+		}
+	}
+	return shifts, nil
+}
+
+func (t timeline) userAt(at time.Time) string {
+	for _, rotation := range t.Rotations {
+		for _, p := range rotation.Periods {
+			if p.Recipient.Type == oncall.TargetUser && !at.Before(p.StartDate) && at.Before(p.EndDate) {
+				return p.Recipient.Name
+			}
+		}
+	}
+	return ""
+}
+
+// Incidents returns the alerts the escalation or its owner team responded to.
+func (og *Client) Incidents(ctx context.Context, policy oncall.Policy, from, to time.Time) ([]oncall.Incident, error) {
+	params := url.Values{}
+	params.Set("query", fmt.Sprintf("createdAt >= %d AND createdAt < %d", from.UnixNano()/1e6, to.UnixNano()/1e6))
+	params.Set("sort", "createdAt")
+	params.Set("order", "asc")
+	alerts, err := list[alert](ctx, og, "v2/alerts", params)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't request alerts: %w", err)
+	}
+
+	responders := map[string]bool{policy.Id: true}
+	for _, team := range policy.Teams {
+		responders[team.Id] = true
+	}
+	incidents := []oncall.Incident{}
+	for _, a := range alerts {
+		if a.CreatedAt.Before(from) || !a.CreatedAt.Before(to) {
+			continue
+		}
+		for _, r := range a.Responders {
+			if responders[r.Id] {
+				incidents = append(incidents, a.toIncident(policy.Id))
+				break
+			}
+		}
+	}
+	return incidents, nil
+}
+
+func (a alert) toIncident(policyId string) oncall.Incident {
+	number := 0
+	fmt.Sscan(a.TinyId, &number)
+	return oncall.Incident{
+		Id:        a.Id,
+		Number:    number,
+		Title:     a.Message,
+		Status:    a.Status,
+		CreatedAt: a.CreatedAt,
+		PolicyId:  policyId,
+	}
+}
+
+// Events returns acknowledgement and closing of an alert from its report.
+// Alerts closed by integrations or the system have no UserId.
+func (og *Client) Events(ctx context.Context, incident oncall.Incident) ([]oncall.Event, error) {
+	params := url.Values{}
+	params.Set("identifierType", "id")
+	var a alert
+	if err := og.get(ctx, "v2/alerts/"+incident.Id, params, &a); err != nil {
+		return nil, fmt.Errorf("Couldn't request alert %s: %w", incident.Id, err)
+	}
+
+	events := []oncall.Event{}
+	if a.Report.AcknowledgedBy != "" {
+		events = append(events, oncall.Event{
+			Type:   oncall.EventAcknowledge,
+			At:     a.CreatedAt.Add(time.Duration(a.Report.AckTime) * time.Millisecond),
+			UserId: username(a.Report.AcknowledgedBy),
+		})
+	}
+	if a.Report.ClosedBy != "" {
+		events = append(events, oncall.Event{
+			Type:   oncall.EventResolve,
+			At:     a.CreatedAt.Add(time.Duration(a.Report.CloseTime) * time.Millisecond),
+			UserId: username(a.Report.ClosedBy),
+		})
+	}
+	return events, nil
+}
+
+// username returns name if it is a username, Opsgenie usernames are email
+// addresses.
+func username(name string) string {
+	if strings.Contains(name, "@") {
+		return name
+	}
+	return ""
+}
+
+func (og *Client) User(ctx context.Context, id string) (oncall.User, error) {
+	var u user
+	if err := og.get(ctx, "v2/users/"+url.PathEscape(id), nil, &u); err != nil {
+		return oncall.User{}, fmt.Errorf("Couldn't request user %s: %w", id, err)
+	}
+	return u.toUser()
+}
+
+// Users returns the members of the given teams.
+func (og *Client) Users(ctx context.Context, teamIds []string) ([]oncall.User, error) {
+	members := map[string]bool{}
+	for _, id := range teamIds {
+		params := url.Values{}
+		params.Set("identifierType", "id")
+		var team struct {
+			Members []struct {
+				User struct {
+					Username string `json:"username"`
+				} `json:"user"`
+			} `json:"members"`
+		}
+		if err := og.get(ctx, "v2/teams/"+id, params, &team); err != nil {
+			return nil, fmt.Errorf("Couldn't request team %s: %w", id, err)
+		}
+		for _, member := range team.Members {
+			members[member.User.Username] = true
+		}
+	}
+
+	users, err := list[user](ctx, og, "v2/users", nil)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't request users: %w", err)
+	}
+	result := []oncall.User{}
+	for _, u := range users {
+		if !members[u.Username] {
+			continue
+		}
+		ou, err := u.toUser()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ou)
+	}
+	return result, nil
+}
+
+// MaintenanceWindows returns no windows, Opsgenie maintenance isn't
+// supported yet.
+func (og *Client) MaintenanceWindows(ctx context.Context, serviceIds []string, from, to time.Time) ([]oncall.MaintenanceWindow, error) {
+	return []oncall.MaintenanceWindow{}, nil
+}
+
+func (u user) toUser() (oncall.User, error) {
+	location, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		return oncall.User{}, fmt.Errorf("Location %s of user %s couldn't be loaded: %w", u.TimeZone, u.Username, err)
+	}
+	return oncall.User{
+		Id:       u.Username,
+		Name:     u.FullName,
+		Email:    u.Username,
+		Location: location,
+	}, nil
+}
@@ -2,19 +2,29 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/discordianfish/pager-hours/gdrive"
 	"github.com/discordianfish/pager-hours/holidays"
+	"github.com/discordianfish/pager-hours/oncall"
+	"github.com/discordianfish/pager-hours/opsgenie"
 	"github.com/discordianfish/pager-hours/pagerduty"
+	"github.com/discordianfish/pager-hours/pool"
 )
 
 const (
@@ -25,6 +35,10 @@ const (
 	sunday   = "sunday"
 	holiday  = "holiday"
 
+	rotation = "rotation"
+	override = "override"
+	direct   = "direct"
+
 	office      = "officehours"
 	officeStart = 10
 	officeEnd   = 18
@@ -36,16 +50,39 @@ const (
 )
 
 var (
-	month         = beginningOfMonth(time.Now())
-	token         = flag.String("pd.token", "", "PagerDuty token.")
-	domain        = flag.String("pd.domain", "", "PagerDuty subdomain/organization.")
-	from          = flag.String("from", month.AddDate(0, -1, 0).Format(shortDate), "Calculate hours after this date.")
-	to            = flag.String("to", month.Format(shortDate), "Calculate hours before this date.")
-	policyId      = flag.String("policy", "", "Escalation policy to get on call hours and incidents from")
-	gRefreshToken = flag.String("gdrive.token", "", "Google Drive oauth refresh token.")
-	clientSecret  = flag.String("gdrive.secret", "", "Google Drive client secret.")
-	gCode         = flag.String("gdrive.code", "", "Google Drive auth code (only needed for new token).")
-	directory     = flag.String("gdrive.directory", "On-Call Hours", "Google Drive directory name where to store spreadsheets.")
+	month          = beginningOfMonth(time.Now())
+	providerName   = flag.String("provider", "pagerduty", "On-call provider to get hours from (pagerduty or opsgenie).")
+	token          = flag.String("pd.token", "", "PagerDuty token.")
+	region         = flag.String("pd.region", string(pagerduty.RegionUS), "PagerDuty service region of the account (us or eu).")
+	apiURL         = flag.String("pd.url", "", "PagerDuty API endpoint, defaults to the one of -pd.region.")
+	oauthClientId  = flag.String("pd.oauth.client-id", "", "Client ID of a scoped PagerDuty OAuth app, used instead of -pd.token.")
+	oauthSecret    = flag.String("pd.oauth.client-secret", "", "Client secret of the scoped PagerDuty OAuth app.")
+	oauthSubdomain = flag.String("pd.oauth.subdomain", "", "PagerDuty account subdomain, e.g. example for example.pagerduty.com.")
+	concurrency    = flag.Int("pd.concurrency", 4, "Maximum concurrent PagerDuty requests.")
+	timeout        = flag.Duration("pd.timeout", time.Minute, "Timeout for a single PagerDuty request.")
+	retries        = flag.Int("pd.retries", pagerduty.DefaultRetryPolicy.MaxAttempts, "Maximum attempts per PagerDuty request.")
+	maxBackoff     = flag.Duration("pd.max-backoff", pagerduty.DefaultRetryPolicy.MaxBackoff, "Maximum delay between retried PagerDuty requests.")
+	cacheDir       = flag.String("pd.cache", "", "Store PagerDuty responses in this directory.")
+	offline        = flag.Bool("offline", false, "Build the report only from responses stored in -pd.cache.")
+	ogToken        = flag.String("og.token", "", "Opsgenie API key.")
+	ogURL          = flag.String("og.url", "https://api.opsgenie.com", "Opsgenie API endpoint.")
+	from           = flag.String("from", month.AddDate(0, -1, 0).Format(shortDate), "Calculate hours after this date.")
+	to             = flag.String("to", month.Format(shortDate), "Calculate hours before this date.")
+	policyId       = flag.String("policy", "", "Escalation policy to get on call hours and incidents from")
+	attribution    = flag.String("attribution", attributeResponders, "Credit incidents to the users who acknowledged/resolved them ("+attributeResponders+") or to whoever was on call when they were created ("+attributeSchedule+").")
+	metricsFile    = flag.String("metrics", "", "Write time to acknowledge/resolve per user and bucket as CSV to this file.")
+	suppressedFile = flag.String("suppressed", "", "Write the incidents which weren't counted, because they were created during maintenance or auto-resolved, as CSV to this file.")
+	incidentSource = flag.String("incidents", "api", "Where to get incidents from: the provider's API (api) or the events received by serve-webhooks (webhooks).")
+	calendarDir    = flag.String("holidays.calendars", "", "Directory with additional holiday calendars (*.json), replacing built-in ones of the same region.")
+	offices        = flag.String("offices", "", "Comma separated email=region pairs of users whose office isn't the one of their time zone, e.g. alice@example.com=DE-BY.")
+	webhookStore   = flag.String("webhooks.store", "webhooks.jsonl", "File the events received by serve-webhooks are stored in.")
+	webhookAddress = flag.String("webhooks.listen-address", ":8080", "Address serve-webhooks listens on.")
+	webhookPath    = flag.String("webhooks.path", "/webhooks", "Path serve-webhooks receives webhooks on.")
+	webhookSecrets = flag.String("webhooks.secret", "", "Comma separated signing secrets of the PagerDuty webhook subscriptions.")
+	gRefreshToken  = flag.String("gdrive.token", "", "Google Drive oauth refresh token.")
+	clientSecret   = flag.String("gdrive.secret", "", "Google Drive client secret.")
+	gCode          = flag.String("gdrive.code", "", "Google Drive auth code (only needed for new token).")
+	directory      = flag.String("gdrive.directory", "On-Call Hours", "Google Drive directory name where to store spreadsheets.")
 )
 
 var (
@@ -55,6 +92,10 @@ var (
 		"Time Zone",
 		"Location",
 		"Type",
+		"Escalation Level",
+		"Layer",
+		"Shift",
+		"Overridden User",
 		"Hours On-Call",
 		"Hours with Incidents/Day",
 		"Hours with Incidents/Night",
@@ -64,11 +105,29 @@ var (
 )
 
 type worker struct {
+	id       string
 	email    string
 	location *time.Location
 	region   holidays.Region
 }
 
+// shift is a schedule entry of the escalation level it was found on.
+// Direct shifts come from users targeted by the escalation rule itself.
+type shift struct {
+	level  int
+	direct bool
+	oncall.Shift
+}
+
+type workKey struct {
+	date       string // UTC day of the hours
+	level      int
+	layer      string
+	bucket     string
+	shift      string
+	overridden string // id of the user who was overridden
+}
+
 type workload struct {
 	oncall         int
 	incidents      int
@@ -79,120 +138,387 @@ func beginningOfMonth(t time.Time) time.Time {
 	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
 }
 
-func bucketFor(t time.Time, user worker) string {
+// bucketFor returns the bucket of t, the local time of user. It fails if
+// the holidays of the user's region aren't known at t, rather than paying a
+// holiday at workday rates.
+func bucketFor(t time.Time, user worker) (string, error) {
 	if t.Weekday() == time.Sunday {
-		return sunday
+		return sunday, nil
 	}
 
-	_, err := holidays.Holiday(t, user.region)
-	if err == nil {
-		return holiday
+	if user.region != "" {
+		_, err := holidays.Holiday(t, user.region)
+		if err == nil {
+			return holiday, nil
+		}
+		if err != holidays.NoHoliday {
+			return "", fmt.Errorf("Couldn't get holidays of %s: %w", user.email, err)
+		}
 	}
 
 	if t.Weekday() == time.Saturday {
-		return saturday
+		return saturday, nil
 	}
 
 	if t.Hour() >= officeStart && t.Hour() < officeEnd {
-		return office
+		return office, nil
 	}
-	return weekday
+	return weekday, nil
 }
 
 type pagerHours struct {
-	officeTZ  map[string]holidays.Region
-	incidents map[string]map[int][]pagerduty.Incident
-	entries   []pagerduty.ScheduleEntries
-	pd        pagerduty.Client
-	policy    *pagerduty.EscalationPolicyDetail
+	officeTZ        map[string]holidays.Region
+	offices         map[string]holidays.Region // by email, overrides officeTZ
+	workers         map[string]worker
+	users           *oncall.UserCache
+	policyIncidents []oncall.Incident
+	suppressed      []suppressed
+	incidents       hourly
+	events          map[string][]oncall.Event // by incident id
+	responses       map[string]hourly
+	attribution     string
+	concurrency     int
+	entries         []shift
+	provider        oncall.Provider
+	policy          *oncall.Policy
 }
 
-func New(officeTZ map[string]holidays.Region) *pagerHours {
-	return &pagerHours{
-		officeTZ: officeTZ,
-		pd:       pagerduty.New(*domain, *token),
+func New(provider oncall.Provider, officeTZ map[string]holidays.Region) *pagerHours {
+	p := &pagerHours{
+		officeTZ:    officeTZ,
+		workers:     map[string]worker{},
+		provider:    provider,
+		attribution: attributeResponders,
+		concurrency: 1,
 	}
+	p.users = oncall.NewUserCache(provider)
+	return p
 }
 
-func (p *pagerHours) setPolicy(policyId string) error {
-	policy, err := p.pd.GetEscalationPolicy(policyId)
+func (p *pagerHours) setPolicy(ctx context.Context, policyId string) error {
+	policy, err := p.provider.Policy(ctx, policyId)
 	if err != nil {
-		return fmt.Errorf("Couldn't get escalation policy: %s", err)
+		return fmt.Errorf("Couldn't get escalation policy: %w", err)
 	}
-	p.policy = policy
+	p.policy = &policy
 	return nil
 }
 
-func (p *pagerHours) getHours(from, to time.Time) error {
+func (p *pagerHours) getHours(ctx context.Context, from, to time.Time) error {
 	if p.policy == nil {
 		return fmt.Errorf("No policy set, use setPolicy(policyId) first!")
 	}
 	log.Printf("Calculating hours for %s between %s and %s", p.policy.Name, from, to)
-	schedule := p.policy.Rules[0].Object // TODO: Verify this is sorted right
-	log.Printf("- Using schedule %s", schedule.Name)
 
-	serviceIds := []string{}
-	for _, service := range p.policy.Services {
-		log.Printf("-- service %s", service.Name)
-		serviceIds = append(serviceIds, service.Id)
+	for _, team := range p.policy.Teams {
+		log.Printf("-- team %s", team.Name)
 	}
 
-	log.Println("- Getting all incidents for services")
-	incidents, err := p.pd.GetIncidents(from, to, serviceIds)
+	log.Println("- Getting all incidents for escalation policy")
+	policyIncidents, err := p.provider.Incidents(ctx, *p.policy, from, to)
 	if err != nil {
-		return fmt.Errorf("Couldn't get incidents: %s", err)
+		return fmt.Errorf("Couldn't get incidents: %w", err)
 	}
 
-	incidentMap := map[string]map[int][]pagerduty.Incident{}
-	for _, incident := range *incidents {
-		if incident.EscalationPolicy.Id != p.policy.Id {
-			continue
+	// Events are needed to tell real interruptions from auto-resolved
+	// incidents, whatever the attribution.
+	log.Println("- Getting log entries for incidents")
+	events := make([][]oncall.Event, len(policyIncidents))
+	err = pool.Run(ctx, p.concurrency, len(policyIncidents), func(ctx context.Context, i int) error {
+		incident := policyIncidents[i]
+		e, err := p.provider.Events(ctx, incident)
+		if err != nil {
+			return fmt.Errorf("Couldn't get log entries for incident %d: %w", incident.Number, err)
 		}
-		c := incident.CreatedOn
-		if _, ok := incidentMap[c.Format(shortDate)]; !ok {
-			incidentMap[c.Format(shortDate)] = map[int][]pagerduty.Incident{}
+		events[i] = e
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	p.events = map[string][]oncall.Event{}
+	for i, incident := range policyIncidents {
+		p.events[incident.Id] = events[i]
+	}
+
+	p.policyIncidents = policyIncidents
+	if err := p.markMaintenance(ctx, from, to); err != nil {
+		return err
+	}
+	p.suppress()
+	if len(p.suppressed) > 0 {
+		log.Printf("- Not counting %d incidents during maintenance or auto-resolved", len(p.suppressed))
+	}
+
+	p.incidents = hourly{}
+	for _, incident := range p.policyIncidents {
+		p.incidents.add(incident.CreatedAt, incident)
+	}
+	p.responses = p.getResponses()
+
+	// Rules are ordered by escalation, the first rule is level 1.
+	targets := []oncall.Reference{}
+	seen := map[string]bool{}
+	for i, rule := range p.policy.Rules {
+		for _, target := range rule.Targets {
+			if target.Type == oncall.TargetUser {
+				continue
+			}
+			if target.Type != oncall.TargetSchedule {
+				log.Printf("- Level %d: skipping %s %s", i+1, target.Type, target.Name)
+				continue
+			}
+			if !seen[target.Id] {
+				seen[target.Id] = true
+				targets = append(targets, target)
+			}
 		}
-		incidentMap[c.Format(shortDate)][c.Hour()] = append(incidentMap[c.Format(shortDate)][c.Hour()], incident)
 	}
 
-	log.Println("- Getting entries for schedule")
-	entries, err := p.pd.GetScheduleEntries(schedule.Id, from, to)
+	log.Printf("- Getting entries for %d schedules", len(targets))
+	schedules := make(map[string][]oncall.Shift, len(targets))
+	var mu sync.Mutex
+	err = pool.Run(ctx, p.concurrency, len(targets), func(ctx context.Context, i int) error {
+		shifts, err := p.provider.Shifts(ctx, targets[i].Id, from, to)
+		if err != nil {
+			return fmt.Errorf("Couldn't get schedule entries for %s: %w", targets[i].Name, err)
+		}
+		mu.Lock()
+		schedules[targets[i].Id] = shifts
+		mu.Unlock()
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("Couldn't get schedule entries for %s: %s", schedule.Name, err)
+		return err
+	}
+
+	entries := []shift{}
+	for i, rule := range p.policy.Rules {
+		for _, target := range rule.Targets {
+			switch target.Type {
+			case oncall.TargetSchedule:
+				for _, s := range schedules[target.Id] {
+					entries = append(entries, shift{level: i + 1, Shift: s})
+				}
+			case oncall.TargetUser:
+				// Users targeted directly are on call for the whole period.
+				entries = append(entries, shift{
+					level:  i + 1,
+					direct: true,
+					Shift:  oncall.Shift{UserId: target.Id, Start: from, End: to},
+				})
+			}
+		}
+	}
+	p.entries = dedupe(entries)
+
+	if err := p.getWorkers(ctx); err != nil {
+		return err
+	}
+	return p.checkHolidays(from, to)
+}
+
+// checkHolidays makes sure the holidays of every worker's region are known
+// on every local day between from and to, so missing dates fail before
+// anything is written.
+func (p *pagerHours) checkHolidays(from, to time.Time) error {
+	for _, w := range p.workers {
+		if w.region == "" {
+			continue
+		}
+		first := from.In(w.location)
+		last := to.Add(-time.Nanosecond).In(w.location)
+		for day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, w.location); !day.After(last); day = day.AddDate(0, 0, 1) {
+			if _, err := holidays.Holiday(day, w.region); err != nil && err != holidays.NoHoliday {
+				return fmt.Errorf("Couldn't get holidays of %s: %w", w.email, err)
+			}
+		}
 	}
-	p.entries = entries
-	p.incidents = incidentMap
 	return nil
 }
 
-func (p *pagerHours) writeFile(file io.ReadWriter) {
-	workers := make(map[string]worker)
+// dedupe credits every UTC hour a user is on call to a single entry, so
+// users on call through more than one target, or with entries split at
+// overrides and layer hand-offs, aren't counted twice for an hour. The hour
+// goes to the entry on the lowest escalation level and among those to the
+// one on call first in the hour. The entries returned start and end on the
+// hour, like the hours incidents are counted in.
+func dedupe(entries []shift) []shift {
+	type userHour struct {
+		user string
+		hour int64
+	}
+	owners := map[userHour]int{}
+	for i, entry := range entries {
+		forHours(entry, func(hour time.Time) {
+			key := userHour{entry.UserId, hour.Unix()}
+			owner, ok := owners[key]
+			if !ok || precedes(entry, entries[owner], hour) {
+				owners[key] = i
+			}
+		})
+	}
+
+	result := []shift{}
+	for i, entry := range entries {
+		var part *shift
+		forHours(entry, func(hour time.Time) {
+			if owners[userHour{entry.UserId, hour.Unix()}] != i {
+				if part != nil {
+					result = append(result, *part)
+					part = nil
+				}
+				return
+			}
+			if part == nil {
+				part = &shift{}
+				*part = entry
+				part.Start = hour
+			}
+			part.End = hour.Add(time.Hour)
+		})
+		if part != nil {
+			result = append(result, *part)
+		}
+	}
+	return result
+}
+
+// forHours calls f with the start of every UTC hour entry is on call in.
+func forHours(entry shift, f func(hour time.Time)) {
+	if !entry.Start.Before(entry.End) {
+		return
+	}
+	for hour := entry.Start.UTC().Truncate(time.Hour); hour.Before(entry.End); hour = hour.Add(time.Hour) {
+		f(hour)
+	}
+}
 
+// precedes reports whether a rather than b gets the hour starting at hour.
+func precedes(a, b shift, hour time.Time) bool {
+	if a.level != b.level {
+		return a.level < b.level
+	}
+	return latest(a.Start, hour).Before(latest(b.Start, hour))
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// getWorkers resolves every user appearing in the report, loading the
+// policy's teams in bulk first.
+func (p *pagerHours) getWorkers(ctx context.Context) error {
+	if len(p.policy.Teams) > 0 {
+		teamIds := []string{}
+		for _, team := range p.policy.Teams {
+			teamIds = append(teamIds, team.Id)
+		}
+		log.Println("- Getting users of teams")
+		if err := p.users.Load(ctx, teamIds); err != nil {
+			return fmt.Errorf("Couldn't get users: %w", err)
+		}
+	}
+
+	ids := []string{}
+	seen := map[string]bool{}
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, entry := range p.entries {
+		add(entry.UserId)
+		if entry.Override {
+			add(entry.OverriddenId)
+		}
+	}
+	for id := range p.responses {
+		add(id)
+	}
+
+	// Users outside the teams are fetched concurrently.
+	workers := make([]worker, len(ids))
+	err := pool.Run(ctx, p.concurrency, len(ids), func(ctx context.Context, i int) error {
+		w, err := p.getUser(ctx, ids[i])
+		workers[i] = w
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for _, w := range workers {
+		p.workers[w.id] = w
+	}
+	return nil
+}
+
+func (p *pagerHours) writeFile(file io.ReadWriter) error {
 	csvw := csv.NewWriter(file)
 	csvw.Write(csvHeaders)
 
-	day := map[worker]map[string]workload{}
+	day := map[worker]map[workKey]workload{}
+	flush := func() {
+		for user, buckets := range day {
+			for key, work := range buckets {
+				if work.oncall == 0 && work.incidents == 0 && work.incidentsNight == 0 {
+					continue
+				}
+				overridden := ""
+				if key.overridden != "" {
+					overridden = p.lookupUser(key.overridden).email
+				}
+				csvw.Write([]string{
+					key.date,
+					user.email,
+					user.location.String(),
+					string(user.region),
+					key.bucket,
+					strconv.Itoa(key.level),
+					key.layer,
+					key.shift,
+					overridden,
+					strconv.Itoa(work.oncall),
+					strconv.Itoa(work.incidents),
+					strconv.Itoa(work.incidentsNight),
+					"0", "0",
+				})
+				csvw.Flush()
+				day[user][key] = workload{}
+			}
+		}
+	}
 
 	for _, entry := range p.entries {
 		current := entry.Start
 		for current.Before(entry.End) {
-			email := entry.User.Email
-			if _, ok := workers[email]; !ok {
-				workers[email] = p.getUser(entry.User.Id)
-			}
-
-			user := workers[email]
+			user := p.lookupUser(entry.UserId)
 			if _, ok := day[user]; !ok {
-				day[user] = map[string]workload{}
+				day[user] = map[workKey]workload{}
 			}
 
 			currentLocal := current.In(user.location) // local time for the user working that hour
-			bucket := bucketFor(currentLocal, user)
+			bucket, err := bucketFor(currentLocal, user)
+			if err != nil {
+				return err
+			}
+			key := workKey{date: current.Format(shortDate), level: entry.level, layer: entry.Layer, bucket: bucket, shift: rotation}
+			if entry.Override {
+				key.shift = override
+				key.overridden = entry.OverriddenId
+			}
+			if entry.direct {
+				key.shift = direct
+			}
 
-			work := day[user][bucket]
+			work := day[user][key]
 			work.oncall++
 
-			incidents := p.incidents[current.Format(shortDate)][current.Hour()]
+			incidents := p.incidentsAt(user, current)
 
 			if len(incidents) > 0 {
 				if currentLocal.Hour() >= nightStart && currentLocal.Hour() < nightEnd {
@@ -201,65 +527,195 @@ func (p *pagerHours) writeFile(file io.ReadWriter) {
 					work.incidents++
 				}
 			}
-			day[user][bucket] = work
+			day[user][key] = work
 
 			next := current.Add(1 * time.Hour)
 			if next.Day() != current.Day() {
-				for user, buckets := range day {
-					for bucket, work := range buckets {
-						if work.oncall == 0 && work.incidents == 0 && work.incidentsNight == 0 {
-							continue
-						}
-						csvw.Write([]string{
-							current.Format(shortDate),
-							user.email,
-							user.location.String(),
-							string(user.region),
-							bucket,
-							strconv.Itoa(work.oncall),
-							strconv.Itoa(work.incidents),
-							strconv.Itoa(work.incidentsNight),
-							"0", "0",
-						})
-						csvw.Flush()
-						day[user][bucket] = workload{}
-					}
-				}
+				flush()
 			}
 			current = next
 		}
 	}
+	flush()
+	return csvw.Error()
 }
 
-func (p *pagerHours) listEscalationPolicies() {
-	policies, err := p.pd.GetEscalationPolicies()
+func (p *pagerHours) listEscalationPolicies(ctx context.Context) {
+	policies, err := p.provider.Policies(ctx)
 	if err != nil {
-		log.Fatalf("Couldn't get policies: %s", err)
+		log.Fatalf("Couldn't get policies: %s", explain(err))
 	}
 
-	for _, policy := range *policies {
+	for _, policy := range policies {
 		fmt.Printf("- %s %s\n", policy.Id, policy.Name)
 	}
 }
 
-func (p *pagerHours) getUser(id string) worker {
-	puser, err := p.pd.GetUser(id)
+// lookupUser returns the worker for a user id resolved by getHours.
+func (p *pagerHours) lookupUser(id string) worker {
+	w, ok := p.workers[id]
+	if !ok {
+		log.Fatalf("User %s wasn't resolved, use getHours first!", id)
+	}
+	return w
+}
+
+func (p *pagerHours) getUser(ctx context.Context, id string) (worker, error) {
+	puser, err := p.users.Get(ctx, id)
 	if err != nil {
-		log.Fatalf("Couldn't get user %s: %s", id, err)
+		return worker{}, fmt.Errorf("Couldn't get user %s: %w", id, err)
 	}
-	region, ok := p.officeTZ[puser.TimeZone]
+	region, ok := p.offices[puser.Email]
 	if !ok {
-		log.Fatalf("No office in %s known", puser.TimeZone)
+		region, ok = p.officeTZ[puser.Location.String()]
+	}
+	if !ok {
+		log.Printf("No office in %s known for %s, ignoring holidays", puser.Location, puser.Email)
 	}
 
 	return worker{
+		id:       puser.Id,
 		email:    puser.Email,
 		location: puser.Location,
 		region:   region,
+	}, nil
+}
+
+// loadCalendars loads the holiday calendars in dir.
+func loadCalendars(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
 	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		region, err := holidays.Load(content)
+		if err != nil {
+			return fmt.Errorf("Couldn't load %s: %w", file, err)
+		}
+		log.Printf("Loaded holidays of %s from %s", region, file)
+	}
+	return nil
+}
+
+// parseOffices parses the -offices flag.
+func parseOffices(s string) (map[string]holidays.Region, error) {
+	offices := map[string]holidays.Region{}
+	if s == "" {
+		return offices, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		email, region, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("Invalid office %q, use email=region", pair)
+		}
+		if !holidays.Supported(holidays.Region(region)) {
+			return nil, fmt.Errorf("Region %s of %s not supported", region, email)
+		}
+		offices[email] = holidays.Region(region)
+	}
+	return offices, nil
+}
+
+// explain adds a hint on how to resolve provider API errors.
+func explain(err error) string {
+	var pdErr *pagerduty.APIError
+	if errors.As(err, &pdErr) {
+		credentials := "-pd.token"
+		if *oauthClientId != "" {
+			credentials = "-pd.oauth.client-id and -pd.oauth.client-secret"
+		}
+		return hint(err, pdErr.StatusCode, pdErr.Temporary(), "PagerDuty", credentials)
+	}
+	var ogErr *opsgenie.APIError
+	if errors.As(err, &ogErr) {
+		return hint(err, ogErr.StatusCode, ogErr.Temporary(), "Opsgenie", "-og.token")
+	}
+	if errors.Is(err, pagerduty.ErrNotCached) {
+		return fmt.Sprintf("%s (run without -offline first to fill -pd.cache)", err)
+	}
+	return err.Error()
 }
 
-func exportGdrive(p *pagerHours, file *bytes.Buffer, fromTime, toTime time.Time) {
+func hint(err error, status int, temporary bool, provider, tokenFlag string) string {
+	switch {
+	case status == http.StatusUnauthorized:
+		return fmt.Sprintf("%s (check %s)", err, tokenFlag)
+	case status == http.StatusForbidden:
+		return fmt.Sprintf("%s (token has no access)", err)
+	case status == http.StatusNotFound:
+		return fmt.Sprintf("%s (unknown ID, run without -policy to list policies)", err)
+	case temporary:
+		return fmt.Sprintf("%s (%s unavailable or rate limited, try again later)", err, provider)
+	}
+	return err.Error()
+}
+
+// newProvider returns the on-call provider selected by flags.
+func newProvider() oncall.Provider {
+	switch *providerName {
+	case "pagerduty":
+		if *token == "" && *oauthClientId == "" && !*offline {
+			log.Fatalf("pager-hours -pd.token=<your-token>")
+		}
+		pdRegion, err := pagerduty.ParseRegion(*region)
+		if err != nil {
+			log.Fatal(err)
+		}
+		retry := pagerduty.DefaultRetryPolicy
+		retry.MaxAttempts = *retries
+		retry.MaxBackoff = *maxBackoff
+		options := []pagerduty.Option{
+			pagerduty.WithRegion(pdRegion),
+			pagerduty.WithTimeout(*timeout),
+			pagerduty.WithRetryPolicy(retry),
+			pagerduty.WithConcurrency(*concurrency),
+		}
+		if *apiURL != "" {
+			options = append(options, pagerduty.WithBaseURL(*apiURL))
+		}
+		if *oauthClientId != "" {
+			if *oauthSecret == "" || *oauthSubdomain == "" {
+				log.Fatalf("pager-hours -pd.oauth.client-id=<id> -pd.oauth.client-secret=<secret> -pd.oauth.subdomain=<subdomain>")
+			}
+			options = append(options, pagerduty.WithOAuth(pagerduty.OAuthConfig{
+				ClientId:     *oauthClientId,
+				ClientSecret: *oauthSecret,
+				Subdomain:    *oauthSubdomain,
+			}))
+		}
+		if *cacheDir != "" {
+			cache, err := pagerduty.NewCache(*cacheDir)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if *offline {
+				options = append(options, pagerduty.WithOffline(cache))
+			} else {
+				options = append(options, pagerduty.WithCache(cache))
+			}
+		} else if *offline {
+			log.Fatalf("pager-hours -offline -pd.cache=<directory>")
+		}
+		pd := pagerduty.New(*token, options...)
+		return &pd
+	case "opsgenie":
+		if *offline {
+			log.Fatalf("-offline is only supported for PagerDuty")
+		}
+		if *ogToken == "" {
+			log.Fatalf("pager-hours -provider=opsgenie -og.token=<your-api-key>")
+		}
+		return opsgenie.New(*ogToken, opsgenie.WithBaseURL(*ogURL))
+	}
+	log.Fatalf("Unknown provider %q, use pagerduty or opsgenie", *providerName)
+	return nil
+}
+
+func exportGdrive(p *pagerHours, file io.Reader, filename string) {
 	if *directory == "" {
 		log.Fatalf("Please specify gdrive.directory!")
 	}
@@ -283,15 +739,16 @@ func exportGdrive(p *pagerHours, file *bytes.Buffer, fromTime, toTime time.Time)
 	}
 	log.Printf("- Policy Directory %s/%s", root.Title, parent.Title)
 
-	filename := fmt.Sprintf("%s - %s.csv", fromTime.Format(shortDate), toTime.Format(shortDate))
-	gd.Upload(file, filename, "text/csv", parent.Id)
+	if _, err := gd.Upload(file, filename, "text/csv", parent.Id); err != nil {
+		log.Fatalf("Couldn't upload %s: %s", filename, err)
+	}
 }
 
 func main() {
 	flag.Parse()
 
-	if *token == "" || *domain == "" {
-		log.Fatalf("pager-hours -pd.token=<your-token> -pd.domain=<subdomain/organization>")
+	if *attribution != attributeResponders && *attribution != attributeSchedule {
+		log.Fatalf("Unknown attribution %q, use %s or %s", *attribution, attributeResponders, attributeSchedule)
 	}
 
 	fromTime, err := time.Parse(shortDate, *from)
@@ -305,37 +762,99 @@ func main() {
 	}
 
 	officeTZ := map[string]holidays.Region{
-		"Bangkok":                    holidays.Bangkok,
-		"Berlin":                     holidays.Berlin,
-		"Sofia":                      holidays.Bulgaria,
-		"Pacific Time (US & Canada)": holidays.California,
-		"Eastern Time (US & Canada)": holidays.NewYork,
+		"Asia/Bangkok":        holidays.Bangkok,
+		"Europe/Berlin":       holidays.Berlin,
+		"Europe/Sofia":        holidays.Bulgaria,
+		"America/Los_Angeles": holidays.California,
+		"America/New_York":    holidays.NewYork,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch flag.Arg(0) {
+	case "":
+	case "serve-webhooks":
+		if err := serveWebhooks(ctx); err != nil {
+			log.Fatalf("Couldn't serve webhooks: %s", err)
+		}
+		return
+	default:
+		log.Fatalf("Unknown command %q, use serve-webhooks or no command to build the report", flag.Arg(0))
+	}
+
+	provider := newProvider()
+	switch *incidentSource {
+	case "api":
+	case "webhooks":
+		if *providerName != "pagerduty" {
+			log.Fatalf("-incidents=webhooks is only supported for PagerDuty")
+		}
+		store, err := pagerduty.OpenWebhookStore(*webhookStore)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+		provider = oncall.WithIncidents(provider, store)
+	default:
+		log.Fatalf("Unknown incident source %q, use api or webhooks", *incidentSource)
 	}
-	p := New(officeTZ)
+
+	if *calendarDir != "" {
+		if err := loadCalendars(*calendarDir); err != nil {
+			log.Fatal(err)
+		}
+	}
+	p := New(provider, officeTZ)
+	if p.offices, err = parseOffices(*offices); err != nil {
+		log.Fatal(err)
+	}
+	p.attribution = *attribution
+	p.concurrency = *concurrency
 
 	if *policyId == "" {
 		fmt.Println("No policy (-policy=abc) specified, available policies:")
-		p.listEscalationPolicies()
+		p.listEscalationPolicies(ctx)
 		os.Exit(0)
 	}
-	if err := p.setPolicy(*policyId); err != nil {
-		log.Fatalf("Couldn't set policy: %s", err)
+	if err := p.setPolicy(ctx, *policyId); err != nil {
+		log.Fatalf("Couldn't set policy: %s", explain(err))
 	}
 
-	if err := p.getHours(fromTime, toTime); err != nil {
-		log.Fatalf("Couldn't get hours for policy %s: ", err)
+	if err := p.getHours(ctx, fromTime, toTime); err != nil {
+		log.Fatalf("Couldn't get hours for policy %s: %s", *policyId, explain(err))
 	}
 
 	file := &bytes.Buffer{}
-	p.writeFile(file)
+	if err := p.writeFile(file); err != nil {
+		log.Fatalf("Couldn't write hours: %s", err)
+	}
+	content := file.Bytes()
 
-	if *clientSecret != "" || *gRefreshToken != "" || *gCode != "" {
-		exportGdrive(p, file, fromTime, toTime)
+	metrics := &bytes.Buffer{}
+	if *metricsFile != "" {
+		if err := p.writeMetrics(metrics); err != nil {
+			log.Fatalf("Couldn't write metrics: %s", err)
+		}
+		if err := ioutil.WriteFile(*metricsFile, metrics.Bytes(), 0644); err != nil {
+			log.Fatalf("Couldn't write metrics: %s", err)
+		}
 	}
 
-	content, err := ioutil.ReadAll(file)
-	if err != nil {
-		log.Fatalf("Couldn't read generated csv: %s", err)
+	if *suppressedFile != "" {
+		suppressed := &bytes.Buffer{}
+		p.writeSuppressed(suppressed)
+		if err := ioutil.WriteFile(*suppressedFile, suppressed.Bytes(), 0644); err != nil {
+			log.Fatalf("Couldn't write suppressed incidents: %s", err)
+		}
 	}
+
+	if *clientSecret != "" || *gRefreshToken != "" || *gCode != "" {
+		period := fmt.Sprintf("%s - %s", fromTime.Format(shortDate), toTime.Format(shortDate))
+		exportGdrive(p, bytes.NewReader(content), period+".csv")
+		if *metricsFile != "" {
+			exportGdrive(p, bytes.NewReader(metrics.Bytes()), period+" - response times.csv")
+		}
+	}
+
 	fmt.Printf("%s", content)
 }
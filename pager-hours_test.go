@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/discordianfish/pager-hours/holidays"
+	"github.com/discordianfish/pager-hours/oncall"
+	"github.com/discordianfish/pager-hours/pagerduty"
+)
+
+// newFakePagerDuty serves the recorded responses in test/fixtures/pagerduty,
+// ignoring the query.
+func newFakePagerDuty(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := os.ReadFile(filepath.Join("test/fixtures/pagerduty", r.URL.Path+".json"))
+		if err != nil {
+			t.Logf("No fixture for %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	}))
+}
+
+func testPagerHours(t *testing.T, ts *httptest.Server, options ...pagerduty.Option) *pagerHours {
+	pd := pagerduty.New("token", append([]pagerduty.Option{pagerduty.WithBaseURL(ts.URL)}, options...)...)
+	return testProvider(t, &pd)
+}
+
+func testProvider(t *testing.T, provider oncall.Provider) *pagerHours {
+	p := New(provider, map[string]holidays.Region{
+		"Europe/Berlin":       holidays.Berlin,
+		"America/Los_Angeles": holidays.California,
+	})
+	p.concurrency = 4
+	if err := p.setPolicy(context.Background(), "PPOLICY"); err != nil {
+		t.Fatalf("Couldn't set policy: %s", err)
+	}
+	from := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	if err := p.getHours(context.Background(), from, from.AddDate(0, 0, 2)); err != nil {
+		t.Fatalf("Couldn't get hours: %s", err)
+	}
+	return p
+}
+
+// readRows returns the csv rows of file without header, sorted.
+func readRows(t *testing.T, file *bytes.Buffer) []string {
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("Couldn't read csv: %s", err)
+	}
+	rows := []string{}
+	for _, record := range records[1:] {
+		rows = append(rows, strings.Join(record, ","))
+	}
+	sort.Strings(rows)
+	return rows
+}
+
+func TestGetHours(t *testing.T) {
+	ts := newFakePagerDuty(t)
+	defer ts.Close()
+
+	p := testPagerHours(t, ts)
+	file := &bytes.Buffer{}
+	if err := p.writeFile(file); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"2023-05-01,alice@example.com,Europe/Berlin,DE-BE,holiday,1,Primary rotation,rotation,,20,1,0,0,0",
+		"2023-05-01,alice@example.com,Europe/Berlin,DE-BE,holiday,2,,direct,,2,0,0,0,0",
+		"2023-05-01,alice@example.com,Europe/Berlin,DE-BE,weekday,1,Primary rotation,rotation,,2,0,1,0,0",
+		"2023-05-01,bob@example.com,America/Los_Angeles,California,officehours,2,Follow the sun,rotation,,7,0,0,0,0",
+		"2023-05-01,bob@example.com,America/Los_Angeles,California,sunday,2,Follow the sun,rotation,,7,0,0,0,0",
+		"2023-05-01,bob@example.com,America/Los_Angeles,California,weekday,1,Primary rotation,override,alice@example.com,2,0,0,0,0",
+		"2023-05-01,bob@example.com,America/Los_Angeles,California,weekday,2,Follow the sun,rotation,,8,0,0,0,0",
+		// Alice overrides Bob for 14:00-15:30 UTC, before her own shift. The
+		// hour of the hand-off counts once, as override.
+		"2023-05-02,alice@example.com,Europe/Berlin,DE-BE,officehours,1,Primary rotation,override,bob@example.com,2,0,0,0,0",
+		"2023-05-02,alice@example.com,Europe/Berlin,DE-BE,officehours,2,,direct,,6,0,0,0,0",
+		"2023-05-02,alice@example.com,Europe/Berlin,DE-BE,weekday,1,Primary rotation,rotation,,8,0,0,0,0",
+		"2023-05-02,alice@example.com,Europe/Berlin,DE-BE,weekday,2,,direct,,8,0,1,0,0",
+		"2023-05-02,bob@example.com,America/Los_Angeles,California,officehours,1,Primary rotation,rotation,,1,0,0,0,0",
+		// Business hours take over from follow the sun at 15:30 UTC, the hour
+		// of the hand-off counts once, as follow the sun.
+		"2023-05-02,bob@example.com,America/Los_Angeles,California,officehours,2,Business hours,rotation,,7,0,0,0,0",
+		"2023-05-02,bob@example.com,America/Los_Angeles,California,weekday,1,Primary rotation,rotation,,13,0,2,0,0",
+		"2023-05-02,bob@example.com,America/Los_Angeles,California,weekday,2,Business hours,rotation,,1,0,0,0,0",
+		"2023-05-02,bob@example.com,America/Los_Angeles,California,weekday,2,Follow the sun,rotation,,2,0,0,0,0",
+	}
+	compareRows(t, readRows(t, file), expected)
+}
+
+func TestDedupe(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2023, 5, 1, hour, minute, 0, 0, time.UTC)
+	}
+	entry := func(level int, layer string, start, end time.Time) shift {
+		return shift{level: level, Shift: oncall.Shift{UserId: "PUSER1", Layer: layer, Start: start, End: end}}
+	}
+	for _, tc := range []struct {
+		name     string
+		entries  []shift
+		expected []shift
+	}{
+		{
+			name:     "overlapping targets",
+			entries:  []shift{entry(1, "X", at(10, 0), at(12, 0)), entry(2, "Y", at(11, 0), at(13, 0))},
+			expected: []shift{entry(1, "X", at(10, 0), at(12, 0)), entry(2, "Y", at(12, 0), at(13, 0))},
+		},
+		{
+			name:     "targets in the same hour",
+			entries:  []shift{entry(1, "Y", at(10, 30), at(11, 0)), entry(2, "X", at(10, 0), at(10, 30))},
+			expected: []shift{entry(1, "Y", at(10, 0), at(11, 0))},
+		},
+		{
+			name:     "layer hand-off within the hour",
+			entries:  []shift{entry(2, "X", at(10, 0), at(12, 30)), entry(2, "Y", at(12, 30), at(14, 0))},
+			expected: []shift{entry(2, "X", at(10, 0), at(13, 0)), entry(2, "Y", at(13, 0), at(14, 0))},
+		},
+		{
+			name:     "lower level later in the hour",
+			entries:  []shift{entry(2, "X", at(10, 0), at(10, 30)), entry(1, "Y", at(10, 30), at(12, 0))},
+			expected: []shift{entry(1, "Y", at(10, 0), at(12, 0))},
+		},
+	} {
+		if got := dedupe(tc.entries); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	ts := newFakePagerDuty(t)
+	defer ts.Close()
+
+	p := testPagerHours(t, ts)
+	file := &bytes.Buffer{}
+	if err := p.writeMetrics(file); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"alice@example.com,holiday,day,1,5.0,5.0,1,30.0,30.0",
+		"alice@example.com,weekday,night,1,5.0,5.0,1,30.0,30.0",
+		"bob@example.com,weekday,night,1,35.0,35.0,1,80.0,80.0",
+	}
+	compareRows(t, readRows(t, file), expected)
+}
+
+func TestWriteSuppressed(t *testing.T) {
+	ts := newFakePagerDuty(t)
+	defer ts.Close()
+
+	p := testPagerHours(t, ts)
+	file := &bytes.Buffer{}
+	p.writeSuppressed(file)
+
+	expected := []string{
+		"5,API restarting,API,2023-05-02T12:15:00Z,maintenance",
+		"6,Disk almost full,Database,2023-05-02T18:00:00Z,auto-resolved",
+	}
+	compareRows(t, readRows(t, file), expected)
+}
+
+func TestWebhookMaintenance(t *testing.T) {
+	ts := newFakePagerDuty(t)
+	defer ts.Close()
+
+	store, err := pagerduty.OpenWebhookStore(filepath.Join(t.TempDir(), "webhooks.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for _, event := range []struct {
+		id, eventType, at string
+	}{
+		{"E1", pagerduty.IncidentTriggered, "2023-05-02T12:15:00Z"},
+		{"E2", pagerduty.IncidentAcknowledged, "2023-05-02T12:20:00Z"},
+	} {
+		at, _ := time.Parse(time.RFC3339, event.at)
+		err := store.Append(pagerduty.WebhookEvent{
+			Id:           event.id,
+			EventType:    event.eventType,
+			ResourceType: "incident",
+			OccurredAt:   at,
+			Agent:        &pagerduty.Reference{Id: "PUSER2", Type: pagerduty.UserReference},
+			Data: []byte(`{"id": "PINC5", "number": 5, "title": "API restarting", "created_at": "2023-05-02T12:15:00Z",
+				"service": {"id": "PSERVICE", "summary": "API"}, "escalation_policy": {"id": "PPOLICY"}}`),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pd := pagerduty.New("token", pagerduty.WithBaseURL(ts.URL))
+	p := testProvider(t, oncall.WithIncidents(&pd, store))
+	file := &bytes.Buffer{}
+	p.writeSuppressed(file)
+	compareRows(t, readRows(t, file), []string{
+		"5,API restarting,API,2023-05-02T12:15:00Z,maintenance",
+	})
+}
+
+func TestOffline(t *testing.T) {
+	ts := newFakePagerDuty(t)
+	defer ts.Close()
+
+	cache, err := pagerduty.NewCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	online := &bytes.Buffer{}
+	if err := testPagerHours(t, ts, pagerduty.WithCache(cache)).writeFile(online); err != nil {
+		t.Fatal(err)
+	}
+	ts.Close()
+
+	offline := &bytes.Buffer{}
+	if err := testPagerHours(t, ts, pagerduty.WithOffline(cache)).writeFile(offline); err != nil {
+		t.Fatal(err)
+	}
+	compareRows(t, readRows(t, offline), readRows(t, online))
+}
+
+func TestUnknownHolidays(t *testing.T) {
+	bangkok, err := time.LoadLocation("Asia/Bangkok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := worker{id: "PUSER3", email: "carol@example.com", location: bangkok, region: holidays.Bangkok}
+	p := &pagerHours{workers: map[string]worker{user.id: user}}
+
+	// The last hour of the year is already in the next one in Bangkok.
+	from := time.Date(2027, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	if err := p.checkHolidays(from, to); err == nil {
+		t.Error("Expected error for unknown holidays on the last local day")
+	}
+	if err := p.checkHolidays(from, to.Add(-8*time.Hour)); err != nil {
+		t.Errorf("Expected holidays to be known in December, got %s", err)
+	}
+	if _, err := bucketFor(to.Add(-time.Hour).In(bangkok), user); err == nil {
+		t.Error("Expected error bucketing an hour with unknown holidays")
+	}
+}
+
+func compareRows(t *testing.T, rows, expected []string) {
+	if len(rows) != len(expected) {
+		t.Fatalf("Expected %d rows, got %d:\n%s", len(expected), len(rows), strings.Join(rows, "\n"))
+	}
+	for i := range rows {
+		if rows[i] != expected[i] {
+			t.Errorf("Row %d: expected %s, got %s", i, expected[i], rows[i])
+		}
+	}
+}
+
+func TestParseOffices(t *testing.T) {
+	offices, err := parseOffices("alice@example.com=DE-BY,bob@example.com=DE-HH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offices["alice@example.com"] != holidays.Bavaria || offices["bob@example.com"] != holidays.Hamburg {
+		t.Errorf("Unexpected offices %v", offices)
+	}
+	for _, invalid := range []string{"alice@example.com", "alice@example.com=DE-XX"} {
+		if _, err := parseOffices(invalid); err == nil {
+			t.Errorf("Expected error parsing %q", invalid)
+		}
+	}
+}
+
+func TestLoadCalendars(t *testing.T) {
+	dir := t.TempDir()
+	calendar := `{"region": "Dublin", "holidays": [{"name": "St. Patrick's Day", "kind": "fixed", "date": "03-17"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "dublin.json"), []byte(calendar), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadCalendars(dir); err != nil {
+		t.Fatal(err)
+	}
+	holiday, err := holidays.Holiday(time.Date(2024, 3, 17, 12, 0, 0, 0, time.UTC), "Dublin")
+	if err != nil || holiday.Name != "St. Patrick's Day" {
+		t.Fatalf("Expected St. Patrick's Day, got %+v, %v", holiday, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "invalid.json"), []byte(`{"region": "Invalid", "holidays": [{"name": "Foo"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadCalendars(dir); err == nil {
+		t.Fatal("Expected error loading invalid calendar")
+	}
+}
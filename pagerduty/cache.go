@@ -0,0 +1,103 @@
+package pagerduty
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotCached is returned in offline mode for requests without a cached
+// response.
+var ErrNotCached = errors.New("response not cached")
+
+// Cache keeps API responses on disk. Response bodies are stored by their
+// sha256 in objects/, every request refers to the body it got in requests/.
+// Identical responses are stored only once and the objects can be verified
+// and archived as the exact inputs of a report.
+type Cache struct {
+	dir string
+}
+
+// NewCache returns a Cache in dir, creating it if necessary.
+func NewCache(dir string) (*Cache, error) {
+	for _, sub := range []string{"objects", "requests"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, fmt.Errorf("Couldn't create cache: %w", err)
+		}
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Get returns the cached response for key, or ErrNotCached.
+func (c *Cache) Get(key string) ([]byte, error) {
+	ref, err := os.ReadFile(c.requestPath(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", key, ErrNotCached)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sum, _, _ := bytes.Cut(ref, []byte(" "))
+	body, err := os.ReadFile(filepath.Join(c.dir, "objects", string(sum)))
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't read cached response for %s: %w", key, err)
+	}
+	if hash(body) != string(sum) {
+		return nil, fmt.Errorf("Cached response for %s is corrupt", key)
+	}
+	return body, nil
+}
+
+// Put stores body as the response for key.
+func (c *Cache) Put(key string, body []byte) error {
+	sum := hash(body)
+	object := filepath.Join(c.dir, "objects", sum)
+	if _, err := os.Stat(object); err != nil {
+		if err := writeFile(object, body); err != nil {
+			return err
+		}
+	}
+	// The key is kept next to the object reference to make the cache
+	// browsable.
+	return writeFile(c.requestPath(key), []byte(sum+" "+key+"\n"))
+}
+
+func (c *Cache) requestPath(key string) string {
+	return filepath.Join(c.dir, "requests", hash([]byte(key)))
+}
+
+// cacheKey identifies a request independent of the endpoint it was sent to.
+func cacheKey(path string, params url.Values) string {
+	return path + "?" + params.Encode()
+}
+
+func hash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeFile writes through a temporary file, so concurrent readers never
+// see partial content.
+func writeFile(name string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-")
+	if err != nil {
+		return fmt.Errorf("Couldn't write cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Couldn't write cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Couldn't write cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("Couldn't write cache: %w", err)
+	}
+	return nil
+}
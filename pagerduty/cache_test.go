@@ -0,0 +1,51 @@
+package pagerduty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheOffline(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"user": {"id": "PUSER"}}`)
+	}))
+
+	dir := t.TempDir()
+	cache, err := NewCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pd := New("token", WithBaseURL(ts.URL), WithCache(cache))
+	for _, path := range []string{"users/PUSER", "users/PUSER2"} {
+		if _, err := pd.getBody(context.Background(), path, nil); err != nil {
+			t.Fatalf("Couldn't get %s: %s", path, err)
+		}
+	}
+	ts.Close()
+
+	objects, err := os.ReadDir(filepath.Join(dir, "objects"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("Expected identical responses to be stored once, got %d objects", len(objects))
+	}
+
+	pd = New("", WithOffline(cache))
+	body, err := pd.getBody(context.Background(), "users/PUSER2", nil)
+	if err != nil {
+		t.Fatalf("Couldn't get cached response: %s", err)
+	}
+	if string(body) != `{"user": {"id": "PUSER"}}` {
+		t.Fatalf("Unexpected cached response: %s", body)
+	}
+	if _, err := pd.getBody(context.Background(), "users/PUSER3", nil); !errors.Is(err, ErrNotCached) {
+		t.Fatalf("Expected ErrNotCached, got: %v", err)
+	}
+}
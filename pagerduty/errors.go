@@ -0,0 +1,53 @@
+package pagerduty
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// APIError is returned for every request PagerDuty answers with a non-200
+// status. Use errors.As to get it from the errors returned by Client.
+type APIError struct {
+	StatusCode int      // HTTP status
+	Code       int      // PagerDuty error code, zero if none was given
+	Message    string   // PagerDuty error message
+	Errors     []string // details, e.g. invalid parameters
+	Path       string   // requested path without query
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s: status %d", e.Path, e.StatusCode)
+	if e.Message != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Message)
+	}
+	if e.Code != 0 {
+		msg = fmt.Sprintf("%s (code %d)", msg, e.Code)
+	}
+	if len(e.Errors) > 0 {
+		msg = fmt.Sprintf("%s: %s", msg, strings.Join(e.Errors, ", "))
+	}
+	return msg
+}
+
+// Temporary reports whether the request may succeed when retried later.
+func (e *APIError) Temporary() bool {
+	return retryable(e.StatusCode)
+}
+
+func newAPIError(path string, status int, body []byte) *APIError {
+	e := &APIError{StatusCode: status, Path: path}
+	var response struct {
+		Error struct {
+			Code    int      `json:"code"`
+			Message string   `json:"message"`
+			Errors  []string `json:"errors"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err == nil {
+		e.Code = response.Error.Code
+		e.Message = response.Error.Message
+		e.Errors = response.Error.Errors
+	}
+	return e
+}
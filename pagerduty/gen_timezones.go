@@ -0,0 +1,128 @@
+//go:build ignore
+
+// gen_timezones generates timezones.go from the ActiveSupport time zone
+// mapping. PagerDuty used these Rails names for user time zones before API
+// v2, and older accounts may still return them.
+//
+//	go run gen_timezones.go [-source <url or file>]
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	source = flag.String("source", "https://raw.githubusercontent.com/rails/rails/v7.1.3/activesupport/lib/active_support/values/time_zone.rb", "Rails time_zone.rb to read the mapping from.")
+	output = flag.String("o", "timezones.go", "File to write.")
+
+	entry = regexp.MustCompile(`^\s*"([^"]+)"\s*=>\s*"([^"]+)",?\s*$`)
+
+	// legacy are names older Rails versions used, which PagerDuty still
+	// returns for users who never changed their time zone.
+	legacy = map[string]string{
+		"Kyev":         "Kyiv",
+		"Ulaan Bataar": "Ulaanbaatar",
+	}
+	// corrections override wrong mappings of older Rails versions.
+	corrections = map[string]string{
+		"Astana": "Asia/Almaty",
+	}
+)
+
+func main() {
+	flag.Parse()
+
+	src, err := read(*source)
+	if err != nil {
+		log.Fatalf("Couldn't read %s: %s", *source, err)
+	}
+	mapping, err := parse(src)
+	if err != nil {
+		log.Fatalf("Couldn't parse %s: %s", *source, err)
+	}
+	for name, current := range legacy {
+		location, ok := mapping[current]
+		if !ok {
+			log.Fatalf("Legacy name %s refers to unknown %s", name, current)
+		}
+		mapping[name] = location
+	}
+	for name, location := range corrections {
+		mapping[name] = location
+	}
+
+	names := []string{}
+	for name, location := range mapping {
+		if _, err := time.LoadLocation(location); err != nil {
+			log.Fatalf("%s maps to unknown location %s: %s", name, location, err)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by gen_timezones.go from %s; DO NOT EDIT.\n\n", *source)
+	fmt.Fprintf(buf, "package pagerduty\n\n")
+	fmt.Fprintf(buf, "// ianaLocation maps Rails time zone names to IANA locations.\n")
+	fmt.Fprintf(buf, "var ianaLocation = map[string]string{\n")
+	for _, name := range names {
+		fmt.Fprintf(buf, "\t%q: %q,\n", name, mapping[name])
+	}
+	fmt.Fprintf(buf, "}\n")
+
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("Couldn't format code: %s", err)
+	}
+	if err := os.WriteFile(*output, code, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func read(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+	resp, err := http.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// parse returns the entries of the MAPPING hash.
+func parse(src []byte) (map[string]string, error) {
+	mapping := map[string]string{}
+	inMapping := false
+	for _, line := range strings.Split(string(src), "\n") {
+		switch {
+		case strings.Contains(line, "MAPPING = {"):
+			inMapping = true
+		case inMapping && strings.TrimSpace(line) == "}":
+			if len(mapping) == 0 {
+				return nil, fmt.Errorf("MAPPING is empty")
+			}
+			return mapping, nil
+		case inMapping:
+			if m := entry.FindStringSubmatch(line); m != nil {
+				mapping[m[1]] = m[2]
+			}
+		}
+	}
+	return nil, fmt.Errorf("MAPPING not found")
+}
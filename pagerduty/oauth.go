@@ -0,0 +1,107 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTokenURL = "https://identity.pagerduty.com/oauth/token"
+	// tokenExpiryDelta renews tokens before they expire, so they don't
+	// expire while a request is in flight.
+	tokenExpiryDelta = time.Minute
+)
+
+// DefaultOAuthScopes are the scopes pager-hours needs.
+var DefaultOAuthScopes = []string{
+	"escalation_policies.read",
+	"incidents.read",
+	"schedules.read",
+	"services.read",
+	"teams.read",
+	"users.read",
+}
+
+// OAuthConfig configures authentication with the client credentials of a
+// scoped OAuth app instead of an API token.
+type OAuthConfig struct {
+	ClientId     string
+	ClientSecret string
+	Subdomain    string   // of the account, e.g. "example" for example.pagerduty.com
+	Scopes       []string // defaults to DefaultOAuthScopes
+	TokenURL     string   // defaults to PagerDuty's identity endpoint
+}
+
+// oauth gets access tokens and renews them when they expire.
+type oauth struct {
+	config OAuthConfig
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// accessToken returns a valid access token, requesting a new one if
+// necessary.
+func (o *oauth) accessToken(ctx context.Context, pd *Client) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.token != "" && time.Now().Add(tokenExpiryDelta).Before(o.expires) {
+		return o.token, nil
+	}
+
+	scopes := o.config.Scopes
+	if len(scopes) == 0 {
+		scopes = DefaultOAuthScopes
+	}
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", o.config.ClientId)
+	form.Set("client_secret", o.config.ClientSecret)
+	form.Set("scope", fmt.Sprintf("as_account-%s.%s %s", pd.region, o.config.Subdomain, strings.Join(scopes, " ")))
+
+	tokenURL := o.config.TokenURL
+	if tokenURL == "" {
+		tokenURL = defaultTokenURL
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", pd.userAgent)
+	resp, err := pd.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Couldn't request access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", &APIError{StatusCode: resp.StatusCode, Message: "couldn't get access token", Path: "oauth/token"}
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("Couldn't unmarshal access token: %w", err)
+	}
+	o.token = token.AccessToken
+	o.expires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return o.token, nil
+}
+
+// invalidate drops the current token, e.g. after it was rejected.
+func (o *oauth) invalidate(token string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.token == token {
+		o.token = ""
+	}
+}
@@ -0,0 +1,101 @@
+package pagerduty
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOAuth(t *testing.T) {
+	issued := 0
+	valid := ""
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if scope := r.FormValue("scope"); scope != "as_account-eu.example users.read" {
+			t.Errorf("Unexpected scope %q", scope)
+		}
+		issued++
+		valid = fmt.Sprintf("token%d", issued)
+		fmt.Fprintf(w, `{"access_token": %q, "token_type": "bearer", "expires_in": 3600}`, valid)
+	})
+	mux.HandleFunc("/users/PUSER", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"user": {"id": "PUSER"}}`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	pd := New("", WithRegion(RegionEU), WithBaseURL(ts.URL), WithOAuth(OAuthConfig{
+		ClientId:     "client",
+		ClientSecret: "secret",
+		Subdomain:    "example",
+		Scopes:       []string{"users.read"},
+		TokenURL:     ts.URL + "/oauth/token",
+	}))
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := pd.GetUser(ctx, "PUSER"); err != nil {
+			t.Fatalf("Couldn't get user: %s", err)
+		}
+	}
+	if issued != 1 {
+		t.Fatalf("Expected token to be reused, got %d tokens", issued)
+	}
+
+	// Revoke the token, the client must renew it transparently.
+	valid = "revoked"
+	if _, err := pd.GetUser(ctx, "PUSER"); err != nil {
+		t.Fatalf("Couldn't get user with renewed token: %s", err)
+	}
+	if issued != 2 {
+		t.Fatalf("Expected a renewed token, got %d tokens", issued)
+	}
+}
+
+func TestOAuthDefaultScopes(t *testing.T) {
+	scope := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope = r.FormValue("scope")
+		fmt.Fprint(w, `{"access_token": "token", "token_type": "bearer", "expires_in": 3600}`)
+	}))
+	defer ts.Close()
+
+	pd := New("", WithOAuth(OAuthConfig{ClientId: "client", ClientSecret: "secret", Subdomain: "example", TokenURL: ts.URL}))
+	if _, err := pd.accessToken(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// Incidents, schedules and maintenance windows of services are read.
+	expected := "as_account-us.example escalation_policies.read incidents.read schedules.read services.read teams.read users.read"
+	if scope != expected {
+		t.Fatalf("Expected scope %q, got %q", expected, scope)
+	}
+}
+
+func TestRegion(t *testing.T) {
+	if pd := New("token", WithRegion(RegionEU)); pd.url != "https://api.eu.pagerduty.com" {
+		t.Fatalf("Unexpected EU endpoint %s", pd.url)
+	}
+	if pd := New("token"); pd.url != "https://api.pagerduty.com" {
+		t.Fatalf("Unexpected default endpoint %s", pd.url)
+	}
+	pd := New("token", WithRegion("ap"))
+	if _, err := pd.getBody(context.Background(), "users", nil); err == nil || !strings.Contains(err.Error(), `"ap"`) {
+		t.Fatalf("Expected requests to fail with unknown region, got %v", err)
+	}
+	if _, err := ParseRegion("EU"); err == nil {
+		t.Fatal("Expected error parsing region EU")
+	}
+	if region, err := ParseRegion("eu"); err != nil || region != RegionEU {
+		t.Fatalf("Expected RegionEU, got %s, %v", region, err)
+	}
+}
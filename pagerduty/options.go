@@ -0,0 +1,109 @@
+package pagerduty
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultUserAgent   = "pager-hours"
+	defaultTimeout     = time.Minute
+	defaultConcurrency = 4
+)
+
+// Option configures a Client, see New.
+type Option func(*Client)
+
+// WithBaseURL sets the API endpoint, e.g. to talk to a proxy or a local fake.
+func WithBaseURL(url string) Option {
+	return func(pd *Client) {
+		pd.url = strings.TrimSuffix(url, "/")
+	}
+}
+
+// WithHTTPClient sets the http.Client used for requests. The client is
+// copied, so later options don't modify it.
+func WithHTTPClient(client *http.Client) Option {
+	return func(pd *Client) {
+		c := *client
+		pd.httpClient = &c
+	}
+}
+
+// WithTransport sets the RoundTripper of the http.Client.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(pd *Client) {
+		pd.httpClient.Transport = transport
+	}
+}
+
+// WithTimeout sets the timeout of every single request attempt.
+func WithTimeout(timeout time.Duration) Option {
+	return func(pd *Client) {
+		pd.httpClient.Timeout = timeout
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(pd *Client) {
+		pd.userAgent = userAgent
+	}
+}
+
+// WithRetryPolicy sets how failed requests are retried.
+func WithRetryPolicy(retry RetryPolicy) Option {
+	return func(pd *Client) {
+		pd.Retry = retry
+	}
+}
+
+// WithConcurrency sets how many requests may run at the same time when
+// fetching the pages of a list.
+func WithConcurrency(n int) Option {
+	return func(pd *Client) {
+		pd.concurrency = n
+	}
+}
+
+// WithCache stores every response in cache.
+func WithCache(cache *Cache) Option {
+	return func(pd *Client) {
+		pd.cache = cache
+	}
+}
+
+// WithOffline answers all requests from cache without talking to the API.
+// Requests which weren't cached before fail with ErrNotCached.
+func WithOffline(cache *Cache) Option {
+	return func(pd *Client) {
+		pd.cache = cache
+		pd.offline = true
+	}
+}
+
+// WithRegion selects the service region of the account, RegionUS or
+// RegionEU. It sets the API endpoint, use WithBaseURL after it to override.
+// With an unknown region all requests fail, see ParseRegion.
+func WithRegion(region Region) Option {
+	return func(pd *Client) {
+		url, ok := regionURLs[region]
+		if !ok {
+			pd.err = fmt.Errorf("Unknown PagerDuty region %q, use %s or %s", region, RegionUS, RegionEU)
+			return
+		}
+		pd.region = region
+		pd.url = url
+	}
+}
+
+// WithOAuth authenticates with the client credentials of a scoped OAuth app
+// instead of the API token. Access tokens are requested when needed and
+// renewed before they expire.
+func WithOAuth(config OAuthConfig) Option {
+	return func(pd *Client) {
+		pd.oauth = &oauth{config: config}
+	}
+}
@@ -1,52 +1,115 @@
 package pagerduty
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/url"
-	"strconv"
-	"strings"
+	"sort"
 	"time"
 )
 
+// Region is the service region of an account, see WithRegion.
+type Region string
+
+// Service regions.
 const (
-	apiUrl       = "https://%s.pagerduty.com/api/v1"
-	dateLayout   = "02-01-2006"
+	RegionUS Region = "us"
+	RegionEU Region = "eu"
+)
+
+var regionURLs = map[Region]string{
+	RegionUS: "https://api.pagerduty.com",
+	RegionEU: "https://api.eu.pagerduty.com",
+}
+
+// ParseRegion returns the service region named s, "us" or "eu".
+func ParseRegion(s string) (Region, error) {
+	region := Region(s)
+	if _, ok := regionURLs[region]; !ok {
+		return "", fmt.Errorf("Unknown region %q, use %s or %s", s, RegionUS, RegionEU)
+	}
+	return region, nil
+}
+
+const (
+	acceptHeader = "application/vnd.pagerduty+json;version=2"
+	dateLayout   = time.RFC3339
 	defaultLimit = 100
 )
 
+// Target types of escalation rules.
+const (
+	ScheduleReference = "schedule_reference"
+	UserReference     = "user_reference"
+)
+
+// Log entry types.
+const (
+	NotifyLogEntry      = "notify_log_entry"
+	AcknowledgeLogEntry = "acknowledge_log_entry"
+	ResolveLogEntry     = "resolve_log_entry"
+	AssignLogEntry      = "assign_log_entry"
+)
+
 type Common struct {
-	Limit  int `json:"limit"`
-	Offset int `json:"offset"`
-	Total  int `json:"total"`
+	Limit  int  `json:"limit"`
+	Offset int  `json:"offset"`
+	Total  int  `json:"total"`
+	More   bool `json:"more"`
+}
+
+// Reference is the abbreviated representation the v2 API uses when one
+// object refers to another.
+type Reference struct {
+	Id      string `json:"id"`
+	Type    string `json:"type"`
+	Summary string `json:"summary"`
 }
 
 type Schedule struct {
-	Today    string `json:"today"`
 	TimeZone string `json:"time_zone"`
 	Name     string `json:"name"`
 	Id       string `json:"id"`
 }
 
-type Schedules struct {
-	Common
-	Schedules []Schedule `json:"schedules"`
-}
-
 type ScheduleDetails struct {
 	Schedule struct {
 		FinalSchedule struct {
 			ScheduleEntries []ScheduleEntries `json:"rendered_schedule_entries"`
 		} `json:"final_schedule"`
+		// Layers are listed from highest to lowest priority.
+		Layers []ScheduleLayer `json:"schedule_layers"`
 	} `json:"schedule"`
 }
 
+type ScheduleLayer struct {
+	Id              string            `json:"id"`
+	Name            string            `json:"name"`
+	ScheduleEntries []ScheduleEntries `json:"rendered_schedule_entries"`
+}
+
 type ScheduleEntries struct {
 	User  UserDetails `json:"user"`
 	End   time.Time   `json:"end"`
 	Start time.Time   `json:"start"`
+	// Layer is the schedule layer the entry comes from, for overrides the
+	// layer which was overridden.
+	Layer Reference `json:"-"`
+	// Override is set if the entry comes from a schedule override,
+	// Overridden is the user who would have been on call without it.
+	Override   bool        `json:"-"`
+	Overridden UserDetails `json:"-"`
+}
+
+type Override struct {
+	Id    string      `json:"id"`
+	User  UserDetails `json:"user"`
+	End   time.Time   `json:"end"`
+	Start time.Time   `json:"start"`
 }
 
 type User struct {
@@ -57,36 +120,54 @@ type UserDetails struct {
 	Id       string `json:"id"`
 	Email    string `json:"email"`
 	Name     string `json:"name"`
+	Summary  string `json:"summary"`
 	TimeZone string `json:"time_zone"`
 	Location *time.Location
 }
 
-type Incidents struct {
-	Common
-	Incidents []Incident `json:"incidents"`
-}
-
 type Incident struct {
-	CreatedOn           time.Time         `json:"created_on"`
-	NumberOfEscalations int               `json:"number_of_escalations"`
-	TriggerSummaryData  map[string]string `json:"trigger_summary_data"`
-	EscalationPolicy    struct {
-		Name string `json:"name"`
+	Id               string    `json:"id"`
+	IncidentNumber   int       `json:"incident_number"`
+	Title            string    `json:"title"`
+	Status           string    `json:"status"`
+	Urgency          string    `json:"urgency"`
+	CreatedOn        time.Time `json:"created_at"`
+	Service          Service   `json:"service"`
+	EscalationPolicy struct {
+		Name string `json:"summary"`
 		Id   string `json:"id"`
 	} `json:"escalation_policy"`
 }
 
+// MaintenanceWindow is a period in which the services don't create
+// incidents.
+type MaintenanceWindow struct {
+	Id          string      `json:"id"`
+	Description string      `json:"description"`
+	StartTime   time.Time   `json:"start_time"`
+	EndTime     time.Time   `json:"end_time"`
+	Services    []Reference `json:"services"`
+}
+
+// LogEntry is one event in the lifecycle of an incident.
+type LogEntry struct {
+	Id        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Agent     Reference   `json:"agent"`     // who caused the event
+	User      Reference   `json:"user"`      // who was notified (notify_log_entry)
+	Assignees []Reference `json:"assignees"` // who it was (re)assigned to (assign_log_entry)
+}
+
 type Service struct {
-	Name string `json:"name"`
+	Name string `json:"summary"`
 	Id   string `json:"id"`
 }
 
 type EscalationRules struct {
-	Id     string `json:"id"`
-	Object struct {
-		Name string `json:"name"`
-		Id   string `json:"id"`
-	} `json:"rule_object"`
+	Id      string      `json:"id"`
+	Delay   int         `json:"escalation_delay_in_minutes"`
+	Targets []Reference `json:"targets"`
 }
 
 type EscalationPolicy struct {
@@ -94,182 +175,354 @@ type EscalationPolicy struct {
 	Policy EscalationPolicyDetail `json:"escalation_policy"`
 }
 
-type EscalationPolicies struct {
-	Common
-	Policies []EscalationPolicyDetail `json:"escalation_policies"`
-}
-
 type EscalationPolicyDetail struct {
 	Id       string            `json:"id"`
 	Name     string            `json:"name"`
 	Services []Service         `json:"services"`
+	Teams    []Reference       `json:"teams"`
 	Rules    []EscalationRules `json:"escalation_rules"`
 }
 
 type Client struct {
-	token string
-	url   string
+	token       string
+	oauth       *oauth
+	region      Region
+	url         string
+	userAgent   string
+	httpClient  *http.Client
+	concurrency int
+	cache       *Cache
+	offline     bool
+	Retry       RetryPolicy
+	err         error // of invalid options, every request fails with it
 }
 
-func New(domain string, token string) (pd Client) {
+func New(token string, options ...Option) (pd Client) {
 	pd.token = token
-	pd.url = fmt.Sprintf(apiUrl, domain)
-
+	pd.region = RegionUS
+	pd.url = regionURLs[RegionUS]
+	pd.userAgent = defaultUserAgent
+	pd.httpClient = &http.Client{Timeout: defaultTimeout}
+	pd.concurrency = defaultConcurrency
+	pd.Retry = DefaultRetryPolicy
+
+	for _, option := range options {
+		option(&pd)
+	}
 	return pd
 }
 
-// FIXME: missing pagination support
-func (pd *Client) getBody(path string, params url.Values) (body []byte, err error) {
+// getBody requests path and returns the response body. Network errors, rate
+// limiting and server errors are retried according to pd.Retry. Responses
+// are stored in pd.cache if set, in offline mode they are only read from it.
+func (pd *Client) getBody(ctx context.Context, path string, params url.Values) ([]byte, error) {
+	if pd.err != nil {
+		return nil, pd.err
+	}
+	if pd.offline {
+		return pd.cache.Get(cacheKey(path, params))
+	}
 	url := fmt.Sprintf("%s/%s?%s", pd.url, path, params.Encode())
-	client := &http.Client{}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+	renewed := false
+	for attempt := 1; ; attempt++ {
+		token, err := pd.accessToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		body, resp, err := pd.request(ctx, url, token)
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && pd.oauth != nil && !renewed {
+			// The token may have been revoked before it expired, get a new
+			// one without counting this as an attempt.
+			pd.oauth.invalidate(token)
+			renewed = true
+			attempt--
+			continue
+		}
+		if err == nil && resp.StatusCode == http.StatusOK {
+			if pd.cache != nil {
+				if err := pd.cache.Put(cacheKey(path, params), body); err != nil {
+					return nil, err
+				}
+			}
+			return body, nil
+		}
+
+		var wait time.Duration
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			err = fmt.Errorf("getBody: %s: %w", url, err)
+		} else {
+			apiErr := newAPIError(path, resp.StatusCode, body)
+			if !apiErr.Temporary() {
+				return nil, apiErr
+			}
+			err = apiErr
+			wait = retryAfter(resp)
+		}
+		if attempt >= pd.Retry.MaxAttempts {
+			return nil, err
+		}
+		if wait <= 0 {
+			wait = pd.Retry.backoff(attempt - 1)
+		}
+		log.Printf("Request to %s failed (%s), retrying in %s", path, err, wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
-	req.Header.Set("Content-Type", "text/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Token token=%s", pd.token))
-	resp, err := client.Do(req)
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != 200 {
-		return body, fmt.Errorf("Status %s != 200", resp.StatusCode)
+// accessToken returns the API token, or an OAuth access token if
+// configured.
+func (pd *Client) accessToken(ctx context.Context) (string, error) {
+	if pd.oauth == nil {
+		return pd.token, nil
 	}
+	return pd.oauth.accessToken(ctx, pd)
+}
 
-	body, err = ioutil.ReadAll(resp.Body)
+func (pd *Client) request(ctx context.Context, url string, token string) ([]byte, *http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", pd.userAgent)
+	req.Header.Set("Accept", acceptHeader)
+	req.Header.Set("Content-Type", "application/json")
+	if pd.oauth != nil {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else {
+		req.Header.Set("Authorization", fmt.Sprintf("Token token=%s", token))
+	}
+	resp, err := pd.httpClient.Do(req)
 	if err != nil {
-		return body, fmt.Errorf("getBody: %s: %s", url, err)
+		return nil, nil, err
 	}
+	defer resp.Body.Close()
 
-	return body, err
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, resp, nil
 }
 
-func (pd *Client) GetUser(id string) (UserDetails, error) {
-	body, err := pd.getBody(fmt.Sprintf("users/%s", id), url.Values{})
+func (pd *Client) GetUser(ctx context.Context, id string) (UserDetails, error) {
+	body, err := pd.getBody(ctx, fmt.Sprintf("users/%s", id), url.Values{})
 	if err != nil {
-		return UserDetails{}, fmt.Errorf("Couldn't request user: %s", err)
+		return UserDetails{}, fmt.Errorf("Couldn't request user: %w", err)
 	}
 
 	var pdu User
 	if err := json.Unmarshal(body, &pdu); err != nil {
-		return UserDetails{}, fmt.Errorf("Couldn't unmarshal response: %s", err)
+		return UserDetails{}, fmt.Errorf("Couldn't unmarshal response: %w", err)
 	}
 	user := pdu.User
-	locationName, ok := ianaLocation[user.TimeZone]
-	if !ok {
-		return UserDetails{}, fmt.Errorf("Timezone %s couldn't be mapped to a location", user.TimeZone)
-	}
-	location, err := time.LoadLocation(locationName)
-	if err != nil {
-		return UserDetails{}, fmt.Errorf("Location %s couldn't be loaded", locationName)
-	}
-	user.Location = location
-
+	user.resolveLocation()
 	return user, nil
 }
 
-func (pd *Client) GetSchedules() ([]Schedule, error) {
-	// FIXME: missing pagination support
-	params := url.Values{}
-	params.Set("limit", strconv.Itoa(defaultLimit))
-
-	body, err := pd.getBody("schedules", params)
+func (pd *Client) GetSchedules(ctx context.Context) ([]Schedule, error) {
+	schedules, err := list[Schedule](ctx, pd, "schedules", "schedules", url.Values{})
 	if err != nil {
-		return []Schedule{}, fmt.Errorf("Couldn't request schedules: %s", err)
-	}
-
-	var pds Schedules
-	if err := json.Unmarshal(body, &pds); err != nil {
-		return []Schedule{}, fmt.Errorf("Couldn't unmarshal response: %s", err)
+		return []Schedule{}, fmt.Errorf("Couldn't request schedules: %w", err)
 	}
-	if pds.Total >= pds.Limit {
-		return pds.Schedules, fmt.Errorf("Pagination not yet supported but necessary since total entries (%d) > limit (%d)", pds.Total, pds.Limit)
-	}
-	return pds.Schedules, err
+	return schedules, nil
 }
 
-func (pd *Client) GetScheduleEntries(id string, since time.Time, until time.Time) ([]ScheduleEntries, error) {
+func (pd *Client) GetScheduleEntries(ctx context.Context, id string, since time.Time, until time.Time) ([]ScheduleEntries, error) {
 	params := url.Values{}
 	params.Set("since", since.Format(dateLayout))
 	params.Set("until", until.Format(dateLayout))
+	params.Set("time_zone", "UTC")
 
-	body, err := pd.getBody(fmt.Sprintf("schedules/%s", id), params)
+	body, err := pd.getBody(ctx, fmt.Sprintf("schedules/%s", id), params)
 	if err != nil {
-		return []ScheduleEntries{}, fmt.Errorf("Couldn't request schedule/%s: %s", id, err)
+		return []ScheduleEntries{}, fmt.Errorf("Couldn't request schedule/%s: %w", id, err)
 	}
 
 	var pdsd ScheduleDetails
 	if err := json.Unmarshal(body, &pdsd); err != nil {
-		return []ScheduleEntries{}, fmt.Errorf("Couldn't unmarshal response: %s", err)
+		return []ScheduleEntries{}, fmt.Errorf("Couldn't unmarshal response: %w", err)
+	}
+
+	overrides, err := pd.GetScheduleOverrides(ctx, id, since, until)
+	if err != nil {
+		return []ScheduleEntries{}, err
+	}
+
+	entries := []ScheduleEntries{}
+	for _, entry := range pdsd.Schedule.FinalSchedule.ScheduleEntries {
+		entries = append(entries, splitByLayer(entry, pdsd.Schedule.Layers, overrides)...)
+	}
+	return entries, nil
+}
+
+// splitByLayer splits a final schedule entry wherever the layer in charge
+// changes or an override starts or ends. It sets the Layer of each part and
+// flags the parts its user covers by an override.
+func splitByLayer(entry ScheduleEntries, layers []ScheduleLayer, overrides []Override) []ScheduleEntries {
+	bounds := []time.Time{entry.Start, entry.End}
+	add := func(ts ...time.Time) {
+		for _, t := range ts {
+			if t.After(entry.Start) && t.Before(entry.End) {
+				bounds = append(bounds, t)
+			}
+		}
+	}
+	for _, layer := range layers {
+		for _, e := range layer.ScheduleEntries {
+			add(e.Start, e.End)
+		}
+	}
+	for _, override := range overrides {
+		add(override.Start, override.End)
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i].Before(bounds[j]) })
+
+	parts := []ScheduleEntries{}
+	for i := 0; i < len(bounds)-1; i++ {
+		if !bounds[i].Before(bounds[i+1]) {
+			continue
+		}
+		part := entry
+		part.Start, part.End = bounds[i], bounds[i+1]
+		layer, ok := layerAt(layers, part.Start)
+		if ok {
+			part.Layer = Reference{Id: layer.Id, Summary: layer.Name}
+		}
+		for _, override := range overrides {
+			if override.User.Id == part.User.Id && !part.Start.Before(override.Start) && part.Start.Before(override.End) {
+				part.Override = true
+				if ok {
+					part.Overridden, _ = userAt(layer.ScheduleEntries, part.Start)
+				}
+				break
+			}
+		}
+		if n := len(parts); n > 0 && parts[n-1].Layer == part.Layer &&
+			parts[n-1].Override == part.Override && parts[n-1].Overridden.Id == part.Overridden.Id {
+			parts[n-1].End = part.End
+			continue
+		}
+		parts = append(parts, part)
 	}
+	return parts
+}
 
-	return pdsd.Schedule.FinalSchedule.ScheduleEntries, err
+// layerAt returns the layer in charge at t, the one with the highest
+// priority which has someone on call.
+func layerAt(layers []ScheduleLayer, t time.Time) (ScheduleLayer, bool) {
+	for _, layer := range layers {
+		if _, ok := userAt(layer.ScheduleEntries, t); ok {
+			return layer, true
+		}
+	}
+	return ScheduleLayer{}, false
 }
 
-func (pd *Client) GetIncidents(since time.Time, until time.Time, services []string) (*[]Incident, error) {
-	incidents := []Incident{}
-	offset := 0
-	limit := defaultLimit
-	total := limit + 1 // make sure the for condition is true
+// userAt returns the user on call at t according to entries.
+func userAt(entries []ScheduleEntries, t time.Time) (UserDetails, bool) {
+	for _, entry := range entries {
+		if !t.Before(entry.Start) && t.Before(entry.End) {
+			return entry.User, true
+		}
+	}
+	return UserDetails{}, false
+}
 
+func (pd *Client) GetScheduleOverrides(ctx context.Context, id string, since time.Time, until time.Time) ([]Override, error) {
 	params := url.Values{}
 	params.Set("since", since.Format(dateLayout))
 	params.Set("until", until.Format(dateLayout))
-	params.Set("limit", strconv.Itoa(limit))
+	params.Set("time_zone", "UTC")
 
-	if len(services) > 0 {
-		params.Set("service", strings.Join(services, ","))
+	overrides, err := list[Override](ctx, pd, fmt.Sprintf("schedules/%s/overrides", id), "overrides", params)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't request overrides for schedule/%s: %w", id, err)
 	}
+	return overrides, nil
+}
 
-	for offset+limit <= total {
-		params.Set("offset", strconv.Itoa(offset))
-		body, err := pd.getBody("incidents", params)
-		if err != nil {
-			return nil, fmt.Errorf("Couldn't request incidents: %s", err)
-		}
+// GetIncidents returns all incidents created between since and until which
+// belong to one of the given teams, or all incidents if no team is given.
+func (pd *Client) GetIncidents(ctx context.Context, since time.Time, until time.Time, teams []string) (*[]Incident, error) {
+	params := url.Values{}
+	params.Set("since", since.Format(dateLayout))
+	params.Set("until", until.Format(dateLayout))
+	params.Set("time_zone", "UTC")
 
-		incs := Incidents{}
-		if err := json.Unmarshal(body, &incs); err != nil {
-			return nil, fmt.Errorf("Couldn't unmarshal response: %s", err)
-		}
-		incidents = append(incidents, incs.Incidents...)
-		offset = incs.Offset + limit
-		total = incs.Total
+	for _, team := range teams {
+		params.Add("team_ids[]", team)
+	}
+
+	incidents, err := list[Incident](ctx, pd, "incidents", "incidents", params)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't request incidents: %w", err)
 	}
 	return &incidents, nil
 }
 
-func (pd *Client) GetEscalationPolicies() (*[]EscalationPolicyDetail, error) {
-	// FIXME: missing pagination support
+// GetMaintenanceWindows returns the maintenance windows of the given
+// services which overlap with the time between since and until.
+func (pd *Client) GetMaintenanceWindows(ctx context.Context, since time.Time, until time.Time, services []string) ([]MaintenanceWindow, error) {
 	params := url.Values{}
-	params.Set("limit", strconv.Itoa(defaultLimit))
+	params.Set("time_zone", "UTC")
+	for _, service := range services {
+		params.Add("service_ids[]", service)
+	}
 
-	body, err := pd.getBody("escalation_policies", params)
+	windows, err := list[MaintenanceWindow](ctx, pd, "maintenance_windows", "maintenance_windows", params)
 	if err != nil {
-		return nil, fmt.Errorf("Couldn't request schedules: %s", err)
+		return nil, fmt.Errorf("Couldn't request maintenance windows: %w", err)
 	}
-
-	policies := EscalationPolicies{}
-	if err := json.Unmarshal(body, &policies); err != nil {
-		return nil, fmt.Errorf("Couldn't unmarshal response: %s", err)
+	result := []MaintenanceWindow{}
+	for _, window := range windows {
+		if window.StartTime.Before(until) && since.Before(window.EndTime) {
+			result = append(result, window)
+		}
 	}
+	return result, nil
+}
+
+// GetIncidentLogEntries returns all log entries of an incident, oldest first.
+func (pd *Client) GetIncidentLogEntries(ctx context.Context, id string) ([]LogEntry, error) {
+	params := url.Values{}
+	params.Set("time_zone", "UTC")
+	params.Set("is_overview", "false")
 
-	if policies.Total >= policies.Limit {
-		return nil, fmt.Errorf("Pagination not yet supported but necessary since total entries (%d) > limit (%d)", policies.Total, policies.Limit)
+	entries, err := list[LogEntry](ctx, pd, fmt.Sprintf("incidents/%s/log_entries", id), "log_entries", params)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't request log entries for incident %s: %w", id, err)
 	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
 
-	return &policies.Policies, nil
+func (pd *Client) GetEscalationPolicies(ctx context.Context) (*[]EscalationPolicyDetail, error) {
+	policies, err := list[EscalationPolicyDetail](ctx, pd, "escalation_policies", "escalation_policies", url.Values{})
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't request escalation policies: %w", err)
+	}
+	return &policies, nil
 }
 
-func (pd *Client) GetEscalationPolicy(id string) (*EscalationPolicyDetail, error) {
-	body, err := pd.getBody(fmt.Sprintf("escalation_policies/%s", id), url.Values{})
+func (pd *Client) GetEscalationPolicy(ctx context.Context, id string) (*EscalationPolicyDetail, error) {
+	body, err := pd.getBody(ctx, fmt.Sprintf("escalation_policies/%s", id), url.Values{})
 	if err != nil {
-		return nil, fmt.Errorf("Couldn't request schedules: %s", err)
+		return nil, fmt.Errorf("Couldn't request escalation policy %s: %w", id, err)
 	}
 
 	policy := EscalationPolicy{}
 	if err := json.Unmarshal(body, &policy); err != nil {
-		return nil, fmt.Errorf("Couldn't unmarshal response: %s", err)
+		return nil, fmt.Errorf("Couldn't unmarshal response: %w", err)
 	}
 
 	return &policy.Policy, nil
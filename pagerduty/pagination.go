@@ -0,0 +1,128 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/discordianfish/pager-hours/pool"
+)
+
+// page holds the pagination fields of a list response. Classic endpoints
+// page by offset and report "more", newer ones return a "next_cursor".
+type page struct {
+	Common
+	NextCursor *string `json:"next_cursor"`
+	cursor     bool    // response uses cursor pagination
+}
+
+// list requests path until all pages are consumed and returns the objects
+// found under key in every response. Once the total of an offset paginated
+// list is known, the remaining pages are fetched concurrently.
+func list[T any](ctx context.Context, pd *Client, path string, key string, params url.Values) ([]T, error) {
+	items := []T{}
+
+	query := cloneValues(params)
+	query.Set("limit", strconv.Itoa(defaultLimit))
+	query.Set("total", "true")
+
+	offset := 0
+	for {
+		p, batch, err := getPage[T](ctx, pd, path, key, query)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, batch...)
+
+		if p.cursor {
+			if p.NextCursor == nil || *p.NextCursor == "" {
+				return items, nil
+			}
+			query.Set("cursor", *p.NextCursor)
+			continue
+		}
+
+		if !p.More || len(batch) == 0 {
+			return items, nil
+		}
+		offset += len(batch)
+
+		if p.Total > offset && pd.concurrency > 1 {
+			rest, more, next, err := getPages[T](ctx, pd, path, key, query, offset, len(batch), p.Total)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, rest...)
+			if !more {
+				return items, nil
+			}
+			offset = next
+		}
+		query.Set("offset", strconv.Itoa(offset))
+	}
+}
+
+// getPages fetches the pages between offset and total concurrently. It
+// returns their items in order, whether the last page reported more items
+// and the offset following it.
+func getPages[T any](ctx context.Context, pd *Client, path, key string, query url.Values, offset, limit, total int) ([]T, bool, int, error) {
+	offsets := []int{}
+	for o := offset; o < total; o += limit {
+		offsets = append(offsets, o)
+	}
+	pages := make([]page, len(offsets))
+	batches := make([][]T, len(offsets))
+
+	err := pool.Run(ctx, pd.concurrency, len(offsets), func(ctx context.Context, i int) error {
+		q := cloneValues(query)
+		q.Set("offset", strconv.Itoa(offsets[i]))
+		p, batch, err := getPage[T](ctx, pd, path, key, q)
+		pages[i], batches[i] = p, batch
+		return err
+	})
+	if err != nil {
+		return nil, false, 0, err
+	}
+
+	items := []T{}
+	for _, batch := range batches {
+		items = append(items, batch...)
+	}
+	last := len(offsets) - 1
+	return items, pages[last].More, offsets[last] + len(batches[last]), nil
+}
+
+func getPage[T any](ctx context.Context, pd *Client, path, key string, query url.Values) (page, []T, error) {
+	var p page
+	body, err := pd.getBody(ctx, path, query)
+	if err != nil {
+		return p, nil, err
+	}
+
+	if err := json.Unmarshal(body, &p); err != nil {
+		return p, nil, fmt.Errorf("Couldn't unmarshal page: %w", err)
+	}
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(body, &response); err != nil {
+		return p, nil, fmt.Errorf("Couldn't unmarshal response: %w", err)
+	}
+	_, p.cursor = response["next_cursor"]
+
+	var batch []T
+	if raw, ok := response[key]; ok {
+		if err := json.Unmarshal(raw, &batch); err != nil {
+			return p, nil, fmt.Errorf("Couldn't unmarshal %s: %w", key, err)
+		}
+	}
+	return p, batch, nil
+}
+
+func cloneValues(values url.Values) url.Values {
+	clone := url.Values{}
+	for k, v := range values {
+		clone[k] = append([]string{}, v...)
+	}
+	return clone
+}
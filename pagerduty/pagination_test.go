@@ -0,0 +1,58 @@
+package pagerduty
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestListOffset(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		more := offset+defaultLimit < 250
+		fmt.Fprintf(w, `{"limit": %d, "offset": %d, "total": 250, "more": %t, "schedules": [`, defaultLimit, offset, more)
+		for i := offset; i < offset+defaultLimit && i < 250; i++ {
+			if i > offset {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"id": "P%d"}`, i)
+		}
+		fmt.Fprint(w, "]}")
+	}))
+	defer ts.Close()
+
+	pd := New("token", WithBaseURL(ts.URL))
+	schedules, err := pd.GetSchedules(context.Background())
+	if err != nil {
+		t.Fatalf("Couldn't get schedules: %s", err)
+	}
+	if len(schedules) != 250 {
+		t.Fatalf("Expected 250 schedules, got %d", len(schedules))
+	}
+	if schedules[249].Id != "P249" {
+		t.Fatalf("Expected last schedule P249, got %s", schedules[249].Id)
+	}
+}
+
+func TestListCursor(t *testing.T) {
+	pages := map[string]string{
+		"":  `{"limit": 100, "next_cursor": "b", "records": [{"id": "1"}, {"id": "2"}]}`,
+		"b": `{"limit": 100, "next_cursor": null, "records": [{"id": "3"}]}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, pages[r.URL.Query().Get("cursor")])
+	}))
+	defer ts.Close()
+
+	pd := New("token", WithBaseURL(ts.URL))
+	records, err := list[Reference](context.Background(), &pd, "audit/records", "records", nil)
+	if err != nil {
+		t.Fatalf("Couldn't list records: %s", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+}
@@ -0,0 +1,187 @@
+package pagerduty
+
+import (
+	"context"
+	"time"
+
+	"github.com/discordianfish/pager-hours/oncall"
+)
+
+var (
+	_ oncall.Provider = &Client{}
+
+	targetTypes = map[string]string{
+		ScheduleReference: oncall.TargetSchedule,
+		UserReference:     oncall.TargetUser,
+	}
+	eventTypes = map[string]string{
+		NotifyLogEntry:      oncall.EventNotify,
+		AcknowledgeLogEntry: oncall.EventAcknowledge,
+		ResolveLogEntry:     oncall.EventResolve,
+		AssignLogEntry:      oncall.EventAssign,
+	}
+)
+
+func (pd *Client) Policies(ctx context.Context) ([]oncall.Policy, error) {
+	policies, err := pd.GetEscalationPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := []oncall.Policy{}
+	for _, policy := range *policies {
+		result = append(result, policy.toPolicy())
+	}
+	return result, nil
+}
+
+func (pd *Client) Policy(ctx context.Context, id string) (oncall.Policy, error) {
+	policy, err := pd.GetEscalationPolicy(ctx, id)
+	if err != nil {
+		return oncall.Policy{}, err
+	}
+	return policy.toPolicy(), nil
+}
+
+func (pd *Client) Shifts(ctx context.Context, scheduleId string, from, to time.Time) ([]oncall.Shift, error) {
+	entries, err := pd.GetScheduleEntries(ctx, scheduleId, from, to)
+	if err != nil {
+		return nil, err
+	}
+	shifts := []oncall.Shift{}
+	for _, entry := range entries {
+		shifts = append(shifts, oncall.Shift{
+			UserId:       entry.User.Id,
+			Start:        entry.Start,
+			End:          entry.End,
+			Layer:        entry.Layer.Summary,
+			Override:     entry.Override,
+			OverriddenId: entry.Overridden.Id,
+		})
+	}
+	return shifts, nil
+}
+
+// Incidents returns the incidents assigned to the policy, whatever service
+// they came from. The API can't filter by escalation policy, so incidents
+// are queried by the policy's teams and filtered here.
+func (pd *Client) Incidents(ctx context.Context, policy oncall.Policy, from, to time.Time) ([]oncall.Incident, error) {
+	teamIds := []string{}
+	for _, team := range policy.Teams {
+		teamIds = append(teamIds, team.Id)
+	}
+	incidents, err := pd.GetIncidents(ctx, from, to, teamIds)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []oncall.Incident{}
+	for _, incident := range *incidents {
+		if incident.EscalationPolicy.Id != policy.Id {
+			continue
+		}
+		result = append(result, oncall.Incident{
+			Id:        incident.Id,
+			Number:    incident.IncidentNumber,
+			Title:     incident.Title,
+			Status:    incident.Status,
+			CreatedAt: incident.CreatedOn,
+			PolicyId:  incident.EscalationPolicy.Id,
+			Service:   oncall.Reference{Id: incident.Service.Id, Name: incident.Service.Name},
+		})
+	}
+	return result, nil
+}
+
+func (pd *Client) MaintenanceWindows(ctx context.Context, serviceIds []string, from, to time.Time) ([]oncall.MaintenanceWindow, error) {
+	windows, err := pd.GetMaintenanceWindows(ctx, from, to, serviceIds)
+	if err != nil {
+		return nil, err
+	}
+	result := []oncall.MaintenanceWindow{}
+	for _, window := range windows {
+		w := oncall.MaintenanceWindow{Start: window.StartTime, End: window.EndTime}
+		for _, service := range window.Services {
+			w.Services = append(w.Services, oncall.Reference{Id: service.Id, Name: service.Summary})
+		}
+		result = append(result, w)
+	}
+	return result, nil
+}
+
+func (pd *Client) Events(ctx context.Context, incident oncall.Incident) ([]oncall.Event, error) {
+	entries, err := pd.GetIncidentLogEntries(ctx, incident.Id)
+	if err != nil {
+		return nil, err
+	}
+	events := []oncall.Event{}
+	for _, entry := range entries {
+		eventType, ok := eventTypes[entry.Type]
+		if !ok {
+			continue
+		}
+		event := oncall.Event{Type: eventType, At: entry.CreatedAt}
+		if entry.Agent.Type == UserReference {
+			event.UserId = entry.Agent.Id
+		}
+		if entry.Type == NotifyLogEntry {
+			event.UserId = entry.User.Id
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (pd *Client) User(ctx context.Context, id string) (oncall.User, error) {
+	user, err := pd.GetUser(ctx, id)
+	if err != nil {
+		return oncall.User{}, err
+	}
+	return user.toUser(), nil
+}
+
+func (pd *Client) Users(ctx context.Context, teamIds []string) ([]oncall.User, error) {
+	users, err := pd.ListUsers(ctx, teamIds)
+	if err != nil {
+		return nil, err
+	}
+	result := []oncall.User{}
+	for _, user := range users {
+		result = append(result, user.toUser())
+	}
+	return result, nil
+}
+
+func (user UserDetails) toUser() oncall.User {
+	return oncall.User{
+		Id:       user.Id,
+		Name:     user.Name,
+		Email:    user.Email,
+		Location: user.Location,
+	}
+}
+
+func (policy EscalationPolicyDetail) toPolicy() oncall.Policy {
+	p := oncall.Policy{Id: policy.Id, Name: policy.Name}
+	for _, service := range policy.Services {
+		p.Services = append(p.Services, oncall.Reference{Id: service.Id, Name: service.Name})
+	}
+	for _, team := range policy.Teams {
+		p.Teams = append(p.Teams, toReference(team))
+	}
+	for _, rule := range policy.Rules {
+		r := oncall.Rule{Id: rule.Id}
+		for _, target := range rule.Targets {
+			r.Targets = append(r.Targets, toReference(target))
+		}
+		p.Rules = append(p.Rules, r)
+	}
+	return p
+}
+
+func toReference(ref Reference) oncall.Reference {
+	r := oncall.Reference{Id: ref.Id, Type: ref.Type, Name: ref.Summary}
+	if t, ok := targetTypes[ref.Type]; ok {
+		r.Type = t
+	}
+	return r
+}
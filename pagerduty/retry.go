@@ -0,0 +1,58 @@
+package pagerduty
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how requests failing with a network error, a 429 or a
+// 5xx status are retried.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first one
+	MinBackoff  time.Duration // base delay, doubled on every attempt
+	MaxBackoff  time.Duration // upper bound for the computed delay
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	MinBackoff:  500 * time.Millisecond,
+	MaxBackoff:  30 * time.Second,
+}
+
+// backoff returns a random delay between zero and the exponential backoff
+// for the given attempt ("full jitter").
+func (r RetryPolicy) backoff(attempt int) time.Duration {
+	d := r.MinBackoff
+	for i := 0; i < attempt && d < r.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.MaxBackoff {
+		d = r.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// retryAfter parses the Retry-After header, given either in seconds or as
+// HTTP date. It returns zero if the header is missing or invalid.
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
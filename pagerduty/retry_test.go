@@ -0,0 +1,76 @@
+package pagerduty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetBodyRetry(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch attempts {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			fmt.Fprint(w, `{"user": {"id": "PUSER"}}`)
+		}
+	}))
+	defer ts.Close()
+
+	pd := New("token", WithBaseURL(ts.URL), WithRetryPolicy(RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}))
+	if _, err := pd.getBody(context.Background(), "users/PUSER", nil); err != nil {
+		t.Fatalf("Expected success after retries, got: %s", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestGetBodyNoRetry(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	pd := New("token", WithBaseURL(ts.URL))
+	pd.Retry.MinBackoff = time.Millisecond
+	if _, err := pd.getBody(context.Background(), "users/PUSER", nil); err == nil {
+		t.Fatal("Expected error for 404")
+	}
+	if attempts != 1 {
+		t.Fatalf("Expected a single attempt, got %d", attempts)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error": {"message": "Not Found", "code": 2100}}`)
+	}))
+	defer ts.Close()
+
+	pd := New("token", WithBaseURL(ts.URL))
+	_, err := pd.GetEscalationPolicy(context.Background(), "PNOPE")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected APIError, got: %s", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Code != 2100 || apiErr.Path != "escalation_policies/PNOPE" {
+		t.Fatalf("Unexpected error: %#v", apiErr)
+	}
+	if apiErr.Temporary() {
+		t.Fatal("404 must not be temporary")
+	}
+}
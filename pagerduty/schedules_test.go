@@ -0,0 +1,36 @@
+package pagerduty
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSplitByLayer(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2023, 5, 1, hour, 0, 0, 0, time.UTC) }
+	x, y := UserDetails{Id: "X"}, UserDetails{Id: "Y"}
+	layers := []ScheduleLayer{{
+		Id:   "PLAYER",
+		Name: "Rotation",
+		ScheduleEntries: []ScheduleEntries{
+			{User: y, Start: at(0), End: at(12)},
+			{User: x, Start: at(12), End: at(18)},
+		},
+	}}
+	overrides := []Override{{User: x, Start: at(10), End: at(12)}}
+
+	parts := splitByLayer(ScheduleEntries{User: x, Start: at(10), End: at(18)}, layers, overrides)
+	if len(parts) != 2 {
+		t.Fatalf("Expected 2 parts, got %+v", parts)
+	}
+	if !parts[0].Override || parts[0].Overridden.Id != "Y" || !parts[0].End.Equal(at(12)) {
+		t.Errorf("Expected override of Y until 12:00, got %+v", parts[0])
+	}
+	if parts[1].Override || parts[1].Overridden.Id != "" || !parts[1].Start.Equal(at(12)) {
+		t.Errorf("Expected own rotation from 12:00, got %+v", parts[1])
+	}
+	for _, part := range parts {
+		if part.Layer.Id != "PLAYER" {
+			t.Errorf("Expected layer PLAYER, got %+v", part)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package pagerduty
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+)
+
+//go:generate go run gen_timezones.go
+
+// ListUsers returns all users, or only the members of the given teams.
+func (pd *Client) ListUsers(ctx context.Context, teamIds []string) ([]UserDetails, error) {
+	params := url.Values{}
+	for _, id := range teamIds {
+		params.Add("team_ids[]", id)
+	}
+
+	users, err := list[UserDetails](ctx, pd, "users", "users", params)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't request users: %w", err)
+	}
+	for i := range users {
+		users[i].resolveLocation()
+	}
+	return users, nil
+}
+
+// resolveLocation sets Location from TimeZone. Time zones which can't be
+// resolved fall back to UTC, so a single misconfigured user doesn't fail the
+// whole report.
+func (user *UserDetails) resolveLocation() {
+	location, err := loadLocation(user.TimeZone)
+	if err != nil {
+		log.Printf("User %s: %s, using UTC", user.Id, err)
+		location = time.UTC
+	}
+	user.Location = location
+}
+
+// loadLocation returns the location of a PagerDuty time zone. v2 returns
+// IANA names, older accounts may still carry Rails names.
+func loadLocation(name string) (*time.Location, error) {
+	if iana, ok := ianaLocation[name]; ok {
+		name = iana
+	}
+	if name == "" {
+		return nil, fmt.Errorf("No time zone set")
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("Time zone %s couldn't be loaded: %w", name, err)
+	}
+	return location, nil
+}
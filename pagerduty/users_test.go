@@ -0,0 +1,46 @@
+package pagerduty
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadLocation(t *testing.T) {
+	for name, expected := range map[string]string{
+		"Europe/Berlin":              "Europe/Berlin",
+		"Berlin":                     "Europe/Berlin",
+		"Pacific Time (US & Canada)": "America/Los_Angeles",
+		"Astana":                     "Asia/Almaty",
+		"Kyev":                       "Europe/Kiev",
+	} {
+		location, err := loadLocation(name)
+		if err != nil {
+			t.Errorf("Couldn't load %s: %s", name, err)
+			continue
+		}
+		if location.String() != expected {
+			t.Errorf("%s: expected %s, got %s", name, expected, location)
+		}
+	}
+	for _, name := range []string{"", "Mars/Olympus_Mons"} {
+		if _, err := loadLocation(name); err == nil {
+			t.Errorf("Expected %q to fail", name)
+		}
+	}
+}
+
+func TestIANALocations(t *testing.T) {
+	for name, location := range ianaLocation {
+		if _, err := time.LoadLocation(location); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+}
+
+func TestResolveLocationFallback(t *testing.T) {
+	user := UserDetails{Id: "PUSER", TimeZone: "Mars/Olympus_Mons"}
+	user.resolveLocation()
+	if user.Location != time.UTC {
+		t.Fatalf("Expected fallback to UTC, got %s", user.Location)
+	}
+}
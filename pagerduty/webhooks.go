@@ -0,0 +1,280 @@
+package pagerduty
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/discordianfish/pager-hours/oncall"
+)
+
+const (
+	signatureHeader = "X-PagerDuty-Signature"
+	maxWebhookSize  = 1 << 20
+)
+
+// Incident event types of v3 webhooks.
+const (
+	IncidentTriggered    = "incident.triggered"
+	IncidentAcknowledged = "incident.acknowledged"
+	IncidentResolved     = "incident.resolved"
+	IncidentEscalated    = "incident.escalated"
+	IncidentReassigned   = "incident.reassigned"
+)
+
+// incidentEvents are the event types whose data is the incident.
+var incidentEvents = map[string]bool{
+	IncidentTriggered:    true,
+	IncidentAcknowledged: true,
+	IncidentResolved:     true,
+	IncidentEscalated:    true,
+	IncidentReassigned:   true,
+}
+
+// WebhookEvent is the event of a v3 webhook delivery.
+type WebhookEvent struct {
+	Id           string          `json:"id"`
+	EventType    string          `json:"event_type"`
+	ResourceType string          `json:"resource_type"`
+	OccurredAt   time.Time       `json:"occurred_at"`
+	Agent        *Reference      `json:"agent"`
+	Data         json.RawMessage `json:"data"`
+}
+
+// WebhookIncident is the data of incident events.
+type WebhookIncident struct {
+	Id               string      `json:"id"`
+	Number           int         `json:"number"`
+	Title            string      `json:"title"`
+	Status           string      `json:"status"`
+	CreatedAt        time.Time   `json:"created_at"`
+	Service          Reference   `json:"service"`
+	EscalationPolicy Reference   `json:"escalation_policy"`
+	Teams            []Reference `json:"teams"`
+	Assignees        []Reference `json:"assignees"`
+}
+
+// VerifySignature reports whether header, the X-PagerDuty-Signature of a
+// delivery, contains a valid signature of body for one of the secrets.
+// Several secrets can be given to rotate them.
+func VerifySignature(body []byte, header string, secrets []string) bool {
+	for _, secret := range secrets {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		expected := "v1=" + hex.EncodeToString(mac.Sum(nil))
+		for _, signature := range strings.Split(header, ",") {
+			if hmac.Equal([]byte(strings.TrimSpace(signature)), []byte(expected)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WebhookHandler verifies webhook deliveries and appends their incident
+// events to store.
+func WebhookHandler(store *WebhookStore, secrets []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookSize))
+		if err != nil {
+			http.Error(w, "Couldn't read body", http.StatusBadRequest)
+			return
+		}
+		if !VerifySignature(body, r.Header.Get(signatureHeader), secrets) {
+			http.Error(w, "Invalid signature", http.StatusUnauthorized)
+			return
+		}
+		var delivery struct {
+			Event WebhookEvent `json:"event"`
+		}
+		if err := json.Unmarshal(body, &delivery); err != nil {
+			http.Error(w, "Couldn't unmarshal event", http.StatusBadRequest)
+			return
+		}
+		event := delivery.Event
+		if event.ResourceType != "incident" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if err := store.Append(event); err != nil {
+			log.Printf("Couldn't store webhook event %s: %s", event.Id, err)
+			http.Error(w, "Couldn't store event", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// WebhookStore keeps webhook events in a file, one JSON object per line. It
+// provides the incidents and their events for reports, see
+// oncall.WithIncidents.
+type WebhookStore struct {
+	mu     sync.Mutex
+	file   *os.File
+	events []WebhookEvent
+	seen   map[string]bool
+}
+
+var _ oncall.IncidentSource = &WebhookStore{}
+
+// OpenWebhookStore opens or creates the store in file.
+func OpenWebhookStore(file string) (*WebhookStore, error) {
+	fd, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't open webhook store: %w", err)
+	}
+	store := &WebhookStore{file: fd, seen: map[string]bool{}}
+	scanner := bufio.NewScanner(fd)
+	scanner.Buffer(nil, maxWebhookSize)
+	for line := 1; scanner.Scan(); line++ {
+		var event WebhookEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			fd.Close()
+			return nil, fmt.Errorf("Couldn't read %s:%d: %w", file, line, err)
+		}
+		store.add(event)
+	}
+	if err := scanner.Err(); err != nil {
+		fd.Close()
+		return nil, fmt.Errorf("Couldn't read %s: %w", file, err)
+	}
+	return store, nil
+}
+
+func (s *WebhookStore) Close() error {
+	return s.file.Close()
+}
+
+// Append stores event. Events delivered more than once are stored once.
+func (s *WebhookStore) Append(event WebhookEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[event.Id] {
+		return nil
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	s.add(event)
+	return nil
+}
+
+func (s *WebhookStore) add(event WebhookEvent) {
+	s.seen[event.Id] = true
+	s.events = append(s.events, event)
+}
+
+// Incidents returns the incidents which were assigned to the policy at some
+// point and created between from and to, oldest first.
+func (s *WebhookStore) Incidents(ctx context.Context, policy oncall.Policy, from, to time.Time) ([]oncall.Incident, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	latest := map[string]WebhookIncident{}
+	assigned := map[string]bool{}
+	for _, event := range s.events {
+		incident, ok, err := event.incident()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		latest[incident.Id] = incident
+		if incident.EscalationPolicy.Id == policy.Id {
+			assigned[incident.Id] = true
+		}
+	}
+
+	result := []oncall.Incident{}
+	for id, incident := range latest {
+		if !assigned[id] || incident.CreatedAt.Before(from) || !incident.CreatedAt.Before(to) {
+			continue
+		}
+		result = append(result, oncall.Incident{
+			Id:        incident.Id,
+			Number:    incident.Number,
+			Title:     incident.Title,
+			Status:    incident.Status,
+			CreatedAt: incident.CreatedAt,
+			PolicyId:  policy.Id,
+			Service:   oncall.Reference{Id: incident.Service.Id, Name: incident.Service.Summary},
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// Events returns the acknowledgements, resolutions and assignments of
+// incident, oldest first.
+func (s *WebhookStore) Events(ctx context.Context, incident oncall.Incident) ([]oncall.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := []oncall.Event{}
+	for _, event := range s.events {
+		data, ok, err := event.incident()
+		if err != nil {
+			return nil, err
+		}
+		if !ok || data.Id != incident.Id {
+			continue
+		}
+		switch event.EventType {
+		case IncidentAcknowledged, IncidentResolved:
+			e := oncall.Event{Type: oncall.EventAcknowledge, At: event.OccurredAt}
+			if event.EventType == IncidentResolved {
+				e.Type = oncall.EventResolve
+			}
+			if event.Agent != nil && event.Agent.Type == UserReference {
+				e.UserId = event.Agent.Id
+			}
+			events = append(events, e)
+		case IncidentTriggered, IncidentEscalated, IncidentReassigned:
+			for _, assignee := range data.Assignees {
+				events = append(events, oncall.Event{Type: oncall.EventAssign, At: event.OccurredAt, UserId: assignee.Id})
+			}
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].At.Before(events[j].At)
+	})
+	return events, nil
+}
+
+// incident returns the incident of lifecycle events, ok is false for other
+// events.
+func (event WebhookEvent) incident() (incident WebhookIncident, ok bool, err error) {
+	if !incidentEvents[event.EventType] {
+		return incident, false, nil
+	}
+	if err := json.Unmarshal(event.Data, &incident); err != nil {
+		return incident, false, fmt.Errorf("Couldn't unmarshal incident of event %s: %w", event.Id, err)
+	}
+	return incident, true, nil
+}
@@ -0,0 +1,119 @@
+package pagerduty
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/discordianfish/pager-hours/oncall"
+)
+
+func sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func post(t *testing.T, url string, body []byte, signature string) int {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(signatureHeader, signature)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"event": {}}`)
+	if !VerifySignature(body, "v1=bogus, "+sign(body, "new"), []string{"old", "new"}) {
+		t.Fatal("Expected signature of rotated secret to be valid")
+	}
+	if VerifySignature(body, sign(body, "other"), []string{"old", "new"}) {
+		t.Fatal("Expected signature of unknown secret to be invalid")
+	}
+	if VerifySignature(body, "", []string{"old"}) {
+		t.Fatal("Expected missing signature to be invalid")
+	}
+}
+
+func TestWebhookStore(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "webhooks.jsonl")
+	store, err := OpenWebhookStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(WebhookHandler(store, []string{"secret"}))
+	defer ts.Close()
+
+	deliveries, err := filepath.Glob("test/fixtures/webhooks/*.json")
+	if err != nil || len(deliveries) == 0 {
+		t.Fatalf("No recorded deliveries: %v", err)
+	}
+	for _, delivery := range deliveries {
+		body, err := os.ReadFile(delivery)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status := post(t, ts.URL, body, sign(body, "wrong")); status != http.StatusUnauthorized {
+			t.Fatalf("%s: expected unsigned delivery to be rejected, got status %d", delivery, status)
+		}
+		// PagerDuty retries deliveries, the second one must not be stored.
+		for i := 0; i < 2; i++ {
+			if status := post(t, ts.URL, body, sign(body, "secret")); status != http.StatusNoContent {
+				t.Fatalf("%s: unexpected status %d", delivery, status)
+			}
+		}
+	}
+	store.Close()
+
+	// Read the events back from disk.
+	store, err = OpenWebhookStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if len(store.events) != len(deliveries) {
+		t.Fatalf("Expected %d events, got %d", len(deliveries), len(store.events))
+	}
+
+	ctx := context.Background()
+	from := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	incidents, err := store.Incidents(ctx, oncall.Policy{Id: "PPOLICY"}, from, from.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(incidents) != 1 || incidents[0].Id != "PINC1" || incidents[0].Status != "resolved" {
+		t.Fatalf("Expected resolved incident PINC1, got %#v", incidents)
+	}
+
+	events, err := store.Events(ctx, incidents[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []oncall.Event{
+		{Type: oncall.EventAssign, At: time.Date(2023, 5, 1, 8, 10, 0, 123e6, time.UTC), UserId: "PUSER1"},
+		{Type: oncall.EventAcknowledge, At: time.Date(2023, 5, 1, 8, 15, 0, 456e6, time.UTC), UserId: "PUSER1"},
+		{Type: oncall.EventResolve, At: time.Date(2023, 5, 1, 8, 40, 0, 789e6, time.UTC), UserId: "PUSER1"},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %#v", len(expected), events)
+	}
+	for i := range events {
+		if events[i].Type != expected[i].Type || !events[i].At.Equal(expected[i].At) || events[i].UserId != expected[i].UserId {
+			t.Errorf("Event %d: expected %#v, got %#v", i, expected[i], events[i])
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Package pool runs independent jobs with bounded concurrency.
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// Run calls fn for every i in [0, n) from at most workers goroutines. The
+// first error cancels the context passed to fn and is returned once all
+// started calls have finished.
+func Run(ctx context.Context, workers, n int, fn func(ctx context.Context, i int) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		jobs     = make(chan int)
+	)
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := fn(ctx, i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
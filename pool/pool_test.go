@@ -0,0 +1,48 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/discordianfish/pager-hours/pool"
+)
+
+func TestRun(t *testing.T) {
+	var running, max, calls int32
+	err := pool.Run(context.Background(), 3, 20, func(ctx context.Context, i int) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		atomic.AddInt32(&calls, 1)
+		atomic.AddInt32(&running, -1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if calls != 20 {
+		t.Fatalf("Expected 20 calls, got %d", calls)
+	}
+	if max > 3 {
+		t.Fatalf("Expected at most 3 concurrent calls, got %d", max)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	failed := errors.New("failed")
+	err := pool.Run(context.Background(), 2, 100, func(ctx context.Context, i int) error {
+		if i == 5 {
+			return failed
+		}
+		return ctx.Err()
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Expected %s, got %v", failed, err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/discordianfish/pager-hours/oncall"
+)
+
+const (
+	// suppressMaintenance marks incidents created during a maintenance
+	// window of their service.
+	suppressMaintenance = "maintenance"
+	// suppressAutoResolved marks incidents nobody acknowledged, which were
+	// resolved by their integration or a timeout.
+	suppressAutoResolved = "auto-resolved"
+)
+
+var suppressedHeaders = []string{
+	"Incident",
+	"Title",
+	"Service",
+	"Created",
+	"Reason",
+}
+
+// suppressed is an incident which isn't counted as an interruption.
+type suppressed struct {
+	oncall.Incident
+	reason string
+}
+
+// markMaintenance marks the incidents in p.policyIncidents which were created
+// during a maintenance window of their service.
+func (p *pagerHours) markMaintenance(ctx context.Context, from, to time.Time) error {
+	serviceIds := []string{}
+	seen := map[string]bool{}
+	for _, incident := range p.policyIncidents {
+		if !seen[incident.Service.Id] {
+			seen[incident.Service.Id] = true
+			serviceIds = append(serviceIds, incident.Service.Id)
+		}
+	}
+	if len(serviceIds) == 0 {
+		return nil
+	}
+	windows, err := p.provider.MaintenanceWindows(ctx, serviceIds, from, to)
+	if err != nil {
+		return fmt.Errorf("Couldn't get maintenance windows: %w", err)
+	}
+	for i, incident := range p.policyIncidents {
+		for _, window := range windows {
+			if inMaintenance(incident, window) {
+				p.policyIncidents[i].Maintenance = true
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func inMaintenance(incident oncall.Incident, window oncall.MaintenanceWindow) bool {
+	if incident.CreatedAt.Before(window.Start) || !incident.CreatedAt.Before(window.End) {
+		return false
+	}
+	for _, service := range window.Services {
+		if service.Id == incident.Service.Id {
+			return true
+		}
+	}
+	return false
+}
+
+// suppressReason returns why an incident isn't a real interruption, or ""
+// if it is one.
+func suppressReason(incident oncall.Incident, events []oncall.Event) string {
+	if incident.Maintenance {
+		return suppressMaintenance
+	}
+	resolved := false
+	for _, event := range events {
+		switch event.Type {
+		case oncall.EventAcknowledge:
+			return ""
+		case oncall.EventResolve:
+			if event.UserId != "" {
+				return ""
+			}
+			resolved = true
+		}
+	}
+	if resolved {
+		return suppressAutoResolved
+	}
+	return ""
+}
+
+// suppress removes the incidents which aren't real interruptions from
+// p.policyIncidents and keeps them in p.suppressed.
+func (p *pagerHours) suppress() {
+	incidents := []oncall.Incident{}
+	p.suppressed = []suppressed{}
+	for _, incident := range p.policyIncidents {
+		if reason := suppressReason(incident, p.events[incident.Id]); reason != "" {
+			p.suppressed = append(p.suppressed, suppressed{Incident: incident, reason: reason})
+			continue
+		}
+		incidents = append(incidents, incident)
+	}
+	p.policyIncidents = incidents
+}
+
+func (p *pagerHours) writeSuppressed(file io.Writer) {
+	csvw := csv.NewWriter(file)
+	csvw.Write(suppressedHeaders)
+	for _, incident := range p.suppressed {
+		csvw.Write([]string{
+			strconv.Itoa(incident.Number),
+			incident.Title,
+			incident.Service.Name,
+			incident.CreatedAt.UTC().Format(time.RFC3339),
+			incident.reason,
+		})
+	}
+	csvw.Flush()
+}
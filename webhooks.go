@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/discordianfish/pager-hours/pagerduty"
+)
+
+// serveWebhooks receives PagerDuty webhooks and stores their incident events
+// until ctx is done.
+func serveWebhooks(ctx context.Context) error {
+	if *webhookSecrets == "" {
+		return fmt.Errorf("No signing secret, use pager-hours -webhooks.secret=<signing secret> serve-webhooks")
+	}
+	store, err := pagerduty.OpenWebhookStore(*webhookStore)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	mux := http.NewServeMux()
+	mux.Handle(*webhookPath, pagerduty.WebhookHandler(store, strings.Split(*webhookSecrets, ",")))
+	server := &http.Server{Addr: *webhookAddress, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Receiving webhooks on %s%s, storing them in %s", *webhookAddress, *webhookPath, *webhookStore)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}